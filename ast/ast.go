@@ -25,7 +25,9 @@ import (
                  <IDENTIFIER> "," ValueList
   ParameterList :== Value |
                     Value "," ParameterList
-  Value ::= <STRING> | <INT> | <NUMBER>
+  Value ::= <STRING> | <INT> | <NUMBER> | <FIELD> | <PARAM>
+  FIELD ::= "@" <IDENTIFIER>
+  PARAM ::= "$" <IDENTIFIER> | "$" <DIGITS>
 
   PARSE RULES:
   - There are 2 types of functions (LOGICAL OPERATOR, FIELD OPERATORS)
@@ -58,6 +60,8 @@ type Function struct {
 	Node
 	Name       token.Token
 	Parameters []interface{}
+	Start      int // Position in Input [Start, End) (in Characters, Tolerant Parser only)
+	End        int
 }
 
 type Filter struct {
@@ -65,9 +69,59 @@ type Filter struct {
 	F *Function
 }
 
+// Query: Filter with Optional Projection, Aggregation, Sort and Limit Clauses
+type Query struct {
+	Node
+	Fields *Fields // OPTIONAL
+	Filter *Filter // OPTIONAL
+	Group  *Group  // OPTIONAL
+	Having *Filter // OPTIONAL (Filter over Group Fields and Aggregate Aliases)
+	Sort   *Sort   // OPTIONAL
+	Limit  *Limit  // OPTIONAL
+}
+
+// Projection (Fields and Aggregates to Return)
+type Fields struct {
+	Node
+	Fields     []token.Token
+	Aggregates []*Aggregate
+}
+
+// Aggregate Function (i.e. count(), sum(size))
+type Aggregate struct {
+	Node
+	Function token.Token
+	Field    *token.Token // OPTIONAL (only for count)
+}
+
+type Group struct {
+	Node
+	Fields []token.Token
+}
+
+type SortField struct {
+	Node
+	Field      token.Token
+	Descending bool
+}
+
+type Sort struct {
+	Node
+	Fields []*SortField
+}
+
+type Limit struct {
+	Node
+	Count  token.Token
+	Offset *token.Token // OPTIONAL
+}
+
 type ParseError struct {
 	Node
+	Code    string // OPTIONAL: Error Code (i.e. limits.ERR_DEPTH)
 	Message string
+	Start   int // Position in Input [Start, End) (in Characters, Tolerant Parser only)
+	End     int
 }
 
 func (vs *Value) ToString() string {
@@ -75,10 +129,22 @@ func (vs *Value) ToString() string {
 		s := strings.ReplaceAll(vs.V.Literal, "*", `\*`)
 		s = strings.ReplaceAll(s, "�", "*")
 		return fmt.Sprintf("\"%s\"", s)
+	} else if vs.V.Type == token.FIELD {
+		return "@" + vs.V.Literal
+	} else if vs.V.Type == token.PARAM {
+		return "$" + vs.V.Literal
 	}
 	return vs.V.Literal
 }
 
+// Regular Expression Source for a STRING Value
+// NOTE: Lexer converts '*' to '\uFFFD' (wildcard) and '\*' to '*' (literal)
+func (vs *Value) Pattern() string {
+	s := strings.ReplaceAll(vs.V.Literal, "*", `\*`)
+	s = strings.ReplaceAll(s, "\uFFFD", "*")
+	return s
+}
+
 func (fs *Filter) ToString() string {
 	if fs.F == nil {
 		return "nil"
@@ -106,6 +172,110 @@ func (fs *Function) ToString() string {
 	return fmt.Sprintf("%s ( %s )", fs.Name.Literal, buffer.String())
 }
 
+func (qs *Query) ToString() string {
+	clauses := make([]string, 0, 6)
+	if qs.Fields != nil {
+		clauses = append(clauses, qs.Fields.ToString())
+	}
+	if qs.Filter != nil {
+		clauses = append(clauses, qs.Filter.ToString())
+	}
+	if qs.Group != nil {
+		clauses = append(clauses, qs.Group.ToString())
+	}
+	if qs.Having != nil {
+		clauses = append(clauses, fmt.Sprintf("having ( %s )", qs.Having.ToString()))
+	}
+	if qs.Sort != nil {
+		clauses = append(clauses, qs.Sort.ToString())
+	}
+	if qs.Limit != nil {
+		clauses = append(clauses, qs.Limit.ToString())
+	}
+	return strings.Join(clauses, " ")
+}
+
+func (fls *Fields) ToString() string {
+	fields := make([]string, 0, len(fls.Fields)+len(fls.Aggregates))
+	for _, f := range fls.Fields {
+		fields = append(fields, f.Literal)
+	}
+	for _, a := range fls.Aggregates {
+		fields = append(fields, a.ToString())
+	}
+	return fmt.Sprintf("fields ( %s )", strings.Join(fields, ", "))
+}
+
+func (as *Aggregate) ToString() string {
+	if as.Field == nil {
+		return fmt.Sprintf("%s()", as.Function.Literal)
+	}
+	return fmt.Sprintf("%s(%s)", as.Function.Literal, as.Field.Literal)
+}
+
+// Name of the Aggregate Result (i.e. count, sum_size)
+func (as *Aggregate) Alias() string {
+	name := strings.ToLower(as.Function.Literal)
+	if as.Field == nil {
+		return name
+	}
+	return name + "_" + strings.ReplaceAll(strings.ToLower(as.Field.Literal), ".", "_")
+}
+
+func (gs *Group) ToString() string {
+	fields := make([]string, 0, len(gs.Fields))
+	for _, f := range gs.Fields {
+		fields = append(fields, f.Literal)
+	}
+	return fmt.Sprintf("group ( %s )", strings.Join(fields, ", "))
+}
+
+func (sfs *SortField) ToString() string {
+	if sfs.Descending {
+		return sfs.Field.Literal + " desc"
+	}
+	return sfs.Field.Literal
+}
+
+func (ss *Sort) ToString() string {
+	fields := make([]string, 0, len(ss.Fields))
+	for _, f := range ss.Fields {
+		fields = append(fields, f.ToString())
+	}
+	return fmt.Sprintf("sort ( %s )", strings.Join(fields, ", "))
+}
+
+func (ls *Limit) ToString() string {
+	if ls.Offset != nil {
+		return fmt.Sprintf("limit ( %s, offset %s )", ls.Count.Literal, ls.Offset.Literal)
+	}
+	return fmt.Sprintf("limit ( %s )", ls.Count.Literal)
+}
+
+// Deep Copy of Filter
+func (fs *Filter) Clone() *Filter {
+	if fs.F == nil {
+		return &Filter{}
+	}
+	return &Filter{F: fs.F.Clone()}
+}
+
+// Deep Copy of Function
+func (fs *Function) Clone() *Function {
+	f := &Function{Name: fs.Name, Parameters: make([]interface{}, 0, len(fs.Parameters)), Start: fs.Start, End: fs.End}
+	for _, pi := range fs.Parameters {
+		switch p := pi.(type) {
+		case *Function:
+			f.Parameters = append(f.Parameters, p.Clone())
+		case *Value:
+			f.Parameters = append(f.Parameters, &Value{V: p.V})
+		default:
+			f.Parameters = append(f.Parameters, pi)
+		}
+	}
+	return f
+}
+
 func (pes *ParseError) ToString() string {
 	return fmt.Sprintf("ERROR [%s]", pes.Message)
 }
@@ -0,0 +1,143 @@
+package binder
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/builder"
+	"github.com/objectvault/filter-parser/limits"
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  BINDING
+  - Named ($user) and Positional ($1) Parameters are looked up by name
+    (i.e. "user" and "1"). Names are simple identifiers, the lexer rejects
+    paths such as $user.id
+  - Go strings are bound as literal values, so a '*' in a bound string is
+    never a wildcard
+  - An *ast.Value can also be bound (i.e. a @field reference)
+  - The template filter is not modified, a bound copy is returned
+  - MATCHES patterns are compiled and length checked (limits.Default) as
+    they are bound, so a bad $param pattern fails here
+  - Bound filters should be run through the Syntax Checker (types and
+    coordinates can only be fully verified once bound)
+*/
+
+// Bind Error Object
+type BindError struct {
+	Message string
+}
+
+func (e *BindError) ToString() string {
+	return e.Message
+}
+
+func Bind(f *ast.Filter, values map[string]interface{}) (*ast.Filter, *BindError) {
+	return bind(f, values, false)
+}
+
+// Bind Parameters that have Values, Leaving the Rest Unbound
+func BindPartial(f *ast.Filter, values map[string]interface{}) (*ast.Filter, *BindError) {
+	return bind(f, values, true)
+}
+
+func bind(f *ast.Filter, values map[string]interface{}, partial bool) (*ast.Filter, *BindError) {
+	if f == nil || f.F == nil {
+		return f, nil
+	}
+
+	bf, e := bindFunction(f.F, values, partial)
+	if e != nil {
+		return nil, e
+	}
+
+	return &ast.Filter{F: bf}, nil
+}
+
+func bindFunction(f *ast.Function, values map[string]interface{}, partial bool) (*ast.Function, *BindError) {
+	bf := &ast.Function{Name: f.Name, Parameters: make([]interface{}, 0, len(f.Parameters))}
+
+	for i, pi := range f.Parameters {
+		switch p := pi.(type) {
+		case *ast.Function:
+			bp, e := bindFunction(p, values, partial)
+			if e != nil {
+				return nil, e
+			}
+			bf.Parameters = append(bf.Parameters, bp)
+		case *ast.Value:
+			bv, e := bindValue(p, values, partial)
+			if e != nil {
+				return nil, e
+			}
+
+			// Bound MATCHES Pattern?
+			if i == 1 && p.V.Type == token.PARAM && bv.V.Type == token.STRING && strings.ToUpper(f.Name.Literal) == "MATCHES" { // YES: Verify it
+				if e := bindPattern(f.Name.Literal, bv); e != nil {
+					return nil, e
+				}
+			}
+			bf.Parameters = append(bf.Parameters, bv)
+		default:
+			return nil, &BindError{Message: fmt.Sprintf("Function [%s] has invalid parameter", f.Name.Literal)}
+		}
+	}
+
+	return bf, nil
+}
+
+func bindValue(v *ast.Value, values map[string]interface{}, partial bool) (*ast.Value, *BindError) {
+	// Is Bind Parameter?
+	if v.V.Type != token.PARAM { // NO: Copy Value
+		return &ast.Value{V: v.V}, nil
+	}
+
+	bv, ok := values[v.V.Literal]
+	if !ok && partial {
+		return &ast.Value{V: v.V}, nil
+	} else if !ok {
+		return nil, &BindError{Message: fmt.Sprintf("Parameter [$%s] has no value", v.V.Literal)}
+	}
+
+	// Binding an AST Value?
+	if av, ok := bv.(*ast.Value); ok { // YES: Copy Value
+		return &ast.Value{V: av.V}, nil
+	}
+
+	lv := builder.ASTLiteral(bv)
+	if lv == nil {
+		return nil, &BindError{Message: fmt.Sprintf("Parameter [$%s] has unsupported type [%T]", v.V.Literal, bv)}
+	}
+
+	return lv, nil
+}
+
+func bindPattern(fname string, v *ast.Value) *BindError {
+	pattern := v.Pattern()
+	max := limits.Default().MaxPatternLength
+
+	// Pattern too Long?
+	if limits.Exceeds(len([]rune(pattern)), max) { // YES
+		return &BindError{Message: fmt.Sprintf("Function [%s] Pattern exceeds maximum length [%d]", strings.ToUpper(fname), max)}
+	}
+
+	// Valid RE2 Regular Expression?
+	if _, err := regexp.Compile(pattern); err != nil { // NO
+		return &BindError{Message: fmt.Sprintf("Function [%s] Invalid Pattern [%s]", strings.ToUpper(fname), err.Error())}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package binder
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/builder"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+	"github.com/objectvault/filter-parser/schema"
+	"github.com/objectvault/filter-parser/syntax"
+	"github.com/objectvault/filter-parser/token"
+)
+
+// Parse Input (Fails Test if Input is not a Valid Filter)
+func parseFilter(t *testing.T, input string) *ast.Filter {
+	r := parser.NewParser(lexer.NewLexer(input)).ParseFilter()
+
+	f, ok := r.(*ast.Filter)
+	if !ok {
+		t.Fatalf("parse [%s] failed. got=%T (%+v)", input, r, r)
+	}
+	return f
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		input    string
+		values   map[string]interface{}
+		expected string
+	}{
+		{`gt(n, $min)`, map[string]interface{}{"min": 5}, "gt ( n, 5 )"},
+		{`eq(a, $1)`, map[string]interface{}{"1": "x"}, "eq ( a, \"x\" )"},
+		{`between(n, $1, $2)`, map[string]interface{}{"1": int64(-1), "2": 2.5}, "between ( n, -1, 2.5 )"},
+		{`and(eq(a, $v), eq(b, $v))`, map[string]interface{}{"v": "y"}, "and ( eq ( a, \"y\" ), eq ( b, \"y\" ) )"},
+		{`eq(a, $v)`, map[string]interface{}{"v": builder.ASTField("b")}, "eq ( a, @b )"},
+		{`eq(a, "x")`, nil, "eq ( a, \"x\" )"},
+	}
+
+	for i, tt := range tests {
+		f := parseFilter(t, tt.input)
+
+		bf, e := Bind(f, tt.values)
+		if e != nil {
+			t.Fatalf("tests[%d] - Bind failed. got=%q", i, e.Message)
+		}
+
+		if got := bf.ToString(); got != tt.expected {
+			t.Fatalf("tests[%d] - filter wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+
+		// Template is not Modified
+		if got := f.ToString(); got != parseFilter(t, tt.input).ToString() {
+			t.Fatalf("tests[%d] - template modified. got=%q", i, got)
+		}
+	}
+}
+
+func TestBindLiteralStrings(t *testing.T) {
+	bf, e := Bind(parseFilter(t, `contains(a, $v)`), map[string]interface{}{"v": "a*b"})
+	if e != nil {
+		t.Fatalf("Bind failed. got=%q", e.Message)
+	}
+
+	// Bound '*' is a Literal, not a Wildcard ('\uFFFD')
+	v := bf.F.Parameters[1].(*ast.Value)
+	if v.V.Type != token.STRING || v.V.Literal != "a*b" {
+		t.Fatalf("value wrong. expected=%q, got=%+v", "a*b", v.V)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		values        map[string]interface{}
+		expectedError string
+	}{
+		{`gt(n, $min)`, nil, "Parameter [$min] has no value"},
+		{`and(eq(a, $1), eq(b, $2))`, map[string]interface{}{"1": "x"}, "Parameter [$2] has no value"},
+		{`eq(a, $v)`, map[string]interface{}{"v": true}, "Parameter [$v] has unsupported type [bool]"},
+		{`eq(a, $v)`, map[string]interface{}{"v": nil}, "Parameter [$v] has unsupported type [<nil>]"},
+		{`eq(a, $v)`, map[string]interface{}{"v": []string{"x"}}, "Parameter [$v] has unsupported type [[]string]"},
+		{`matches(a, $p)`, map[string]interface{}{"p": "a(b"}, "Function [MATCHES] Invalid Pattern [error parsing regexp: missing closing ): `a(b`]"},
+		{`not(matches(a, $p))`, map[string]interface{}{"p": "("}, "Function [MATCHES] Invalid Pattern [error parsing regexp: missing closing ): `(`]"},
+		{`matches(a, $p)`, map[string]interface{}{"p": strings.Repeat("a", 257)}, "Function [MATCHES] Pattern exceeds maximum length [256]"},
+	}
+
+	for i, tt := range tests {
+		_, e := Bind(parseFilter(t, tt.input), tt.values)
+		if e == nil || e.Message != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%+v", i, tt.expectedError, e)
+		}
+	}
+}
+
+// Valid Bound Patterns (a '*' in a Bound Pattern is a Literal)
+func TestBindPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		values   map[string]interface{}
+		expected string
+	}{
+		{`matches(a, $p)`, map[string]interface{}{"p": "^a(b|c)$"}, "matches ( a, \"^a(b|c)$\" )"},
+		{`matches(a, $p)`, map[string]interface{}{"p": "a.*"}, "matches ( a, \"a.\\*\" )"},
+		{`matches(a, $p)`, map[string]interface{}{"p": strings.Repeat("a", 256)}, "matches ( a, \"" + strings.Repeat("a", 256) + "\" )"},
+	}
+
+	for i, tt := range tests {
+		bf, e := Bind(parseFilter(t, tt.input), tt.values)
+		if e != nil {
+			t.Fatalf("tests[%d] - Bind failed. got=%q", i, e.Message)
+		}
+
+		if got := bf.ToString(); got != tt.expected {
+			t.Fatalf("tests[%d] - filter wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestBindPartial(t *testing.T) {
+	bf, e := BindPartial(parseFilter(t, `and(eq(a, $1), gt(n, $2))`), map[string]interface{}{"1": "x"})
+	if e != nil {
+		t.Fatalf("BindPartial failed. got=%q", e.Message)
+	}
+
+	expected := "and ( eq ( a, \"x\" ), gt ( n, $2 ) )"
+	if got := bf.ToString(); got != expected {
+		t.Fatalf("filter wrong. expected=%q, got=%q", expected, got)
+	}
+}
+
+// Bound Values are Type Checked when the Bound Filter is Verified
+func TestBindTypeMismatch(t *testing.T) {
+	s := schema.NewSchema()
+	s.AddField("a", token.STRING)
+	s.AddField("n", token.INT)
+
+	tests := []struct {
+		input         string
+		values        map[string]interface{}
+		expectedError string
+	}{
+		{`gt(n, $v)`, map[string]interface{}{"v": 1}, ""},
+		{`gt(n, $v)`, map[string]interface{}{"v": 1.5}, ""},
+		{`gt(n, $v)`, map[string]interface{}{"v": "1"}, "Function [GT] Field [n] expects a value of type [INT] not [STRING]"},
+		{`eq(a, $v)`, map[string]interface{}{"v": 1}, "Function [EQ] Field [a] expects a value of type [STRING] not [INT]"},
+		{`contains(a, $v)`, map[string]interface{}{"v": 1}, "Function [CONTAINS] Parameter 2 should be a String no [INT]"},
+		{`eq(a, $v)`, map[string]interface{}{"v": builder.ASTField("n")}, "Function [EQ] Field [a] of type [STRING] can't be compared to Field [n] of type [INT]"},
+	}
+
+	for i, tt := range tests {
+		bf, e := Bind(parseFilter(t, tt.input), tt.values)
+		if e != nil {
+			t.Fatalf("tests[%d] - Bind failed. got=%q", i, e.Message)
+		}
+
+		c := syntax.NewSyntaxChecker(bf)
+		c.Schema = s
+
+		got := ""
+		if se := c.Verify(); se != nil {
+			got = se.Message
+		}
+
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
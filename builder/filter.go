@@ -11,6 +11,7 @@ package builder
  */
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/objectvault/filter-parser/ast"
@@ -38,6 +39,43 @@ func ASTValue(t token.TokenType, v string) *ast.Value {
 	return &ast.Value{V: buildToken(t, v)}
 }
 
+// Convert Go Value to AST Value (nil - unsupported type)
+func ASTLiteral(v interface{}) *ast.Value {
+	switch n := v.(type) {
+	case string:
+		return ASTValue(token.STRING, n)
+	case int:
+		return ASTValue(token.INT, strconv.FormatInt(int64(n), 10))
+	case int8:
+		return ASTValue(token.INT, strconv.FormatInt(int64(n), 10))
+	case int16:
+		return ASTValue(token.INT, strconv.FormatInt(int64(n), 10))
+	case int32:
+		return ASTValue(token.INT, strconv.FormatInt(int64(n), 10))
+	case int64:
+		return ASTValue(token.INT, strconv.FormatInt(n, 10))
+	case uint:
+		return ASTValue(token.INT, strconv.FormatUint(uint64(n), 10))
+	case uint8:
+		return ASTValue(token.INT, strconv.FormatUint(uint64(n), 10))
+	case uint16:
+		return ASTValue(token.INT, strconv.FormatUint(uint64(n), 10))
+	case uint32:
+		return ASTValue(token.INT, strconv.FormatUint(uint64(n), 10))
+	case uint64:
+		return ASTValue(token.INT, strconv.FormatUint(n, 10))
+	case float32:
+		return ASTValue(token.NUMBER, strconv.FormatFloat(float64(n), 'f', -1, 32))
+	case float64:
+		return ASTValue(token.NUMBER, strconv.FormatFloat(n, 'f', -1, 64))
+	}
+	return nil
+}
+
+func ASTField(field string) *ast.Value {
+	return ASTValue(token.FIELD, strings.ToLower(field))
+}
+
 func ASTEQ(field string, value *ast.Value) *ast.Function {
 	return buildOperatorFunction("EQ", field, value)
 }
@@ -70,6 +108,76 @@ func ASTIN(field string, value *ast.Value) *ast.Function {
 	return buildOperatorFunction("IN", field, value)
 }
 
+func ASTINLIST(field string, values ...*ast.Value) *ast.Function {
+	return buildVariadicFunction("IN", field, values)
+}
+
+func ASTSTARTSWITH(field string, value *ast.Value) *ast.Function {
+	return buildOperatorFunction("STARTSWITH", field, value)
+}
+
+func ASTENDSWITH(field string, value *ast.Value) *ast.Function {
+	return buildOperatorFunction("ENDSWITH", field, value)
+}
+
+func ASTICONTAINS(field string, value *ast.Value) *ast.Function {
+	return buildOperatorFunction("ICONTAINS", field, value)
+}
+
+func ASTIEQ(field string, value *ast.Value) *ast.Function {
+	return buildOperatorFunction("IEQ", field, value)
+}
+
+func ASTMATCHES(field string, value *ast.Value) *ast.Function {
+	return buildOperatorFunction("MATCHES", field, value)
+}
+
+func ASTEXISTS(field string) *ast.Function {
+	return buildUnaryFunction("EXISTS", ASTValue(token.IDENT, strings.ToLower(field)))
+}
+
+func ASTMISSING(field string) *ast.Function {
+	return buildUnaryFunction("MISSING", ASTValue(token.IDENT, strings.ToLower(field)))
+}
+
+func ASTBETWEEN(field string, low *ast.Value, high *ast.Value) *ast.Function {
+	fname := buildToken(token.IDENT, "BETWEEN")
+	lhs := ASTValue(token.IDENT, strings.ToLower(field))
+	f := &ast.Function{Name: fname, Parameters: []interface{}{lhs, low, high}}
+	return f
+}
+
+func ASTHAS(field string, value *ast.Value) *ast.Function {
+	return buildOperatorFunction("HAS", field, value)
+}
+
+func ASTHASANY(field string, values ...*ast.Value) *ast.Function {
+	return buildVariadicFunction("HAS_ANY", field, values)
+}
+
+func ASTHASALL(field string, values ...*ast.Value) *ast.Function {
+	return buildVariadicFunction("HAS_ALL", field, values)
+}
+
+func ASTSEARCH(fields []string, terms *ast.Value) *ast.Function {
+	fname := buildToken(token.IDENT, "SEARCH")
+	params := make([]interface{}, 0, len(fields)+1)
+	for _, field := range fields {
+		params = append(params, ASTValue(token.IDENT, strings.ToLower(field)))
+	}
+	params = append(params, terms)
+	f := &ast.Function{Name: fname, Parameters: params}
+	return f
+}
+
+func ASTNEAR(field string, lat *ast.Value, lon *ast.Value, radius *ast.Value) *ast.Function {
+	return buildVariadicFunction("NEAR", field, []*ast.Value{lat, lon, radius})
+}
+
+func ASTWITHINBOX(field string, lat1 *ast.Value, lon1 *ast.Value, lat2 *ast.Value, lon2 *ast.Value) *ast.Function {
+	return buildVariadicFunction("WITHIN_BOX", field, []*ast.Value{lat1, lon1, lat2, lon2})
+}
+
 func buildUnaryFunction(name string, p interface{}) *ast.Function {
 	fname := buildToken(token.IDENT, name)
 	f := &ast.Function{Name: fname, Parameters: []interface{}{p}}
@@ -89,6 +197,16 @@ func buildOperatorFunction(name string, field string, value *ast.Value) *ast.Fun
 	return f
 }
 
+func buildVariadicFunction(name string, field string, values []*ast.Value) *ast.Function {
+	fname := buildToken(token.IDENT, name)
+	params := []interface{}{ASTValue(token.IDENT, strings.ToLower(field))}
+	for _, v := range values {
+		params = append(params, v)
+	}
+	f := &ast.Function{Name: fname, Parameters: params}
+	return f
+}
+
 func buildToken(t token.TokenType, v string) token.Token {
 	return token.Token{Type: t, Literal: v}
 }
@@ -0,0 +1,36 @@
+package builder
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+)
+
+func TestIN(t *testing.T) {
+	tests := []struct {
+		f        *ast.Function
+		expected string
+	}{
+		{ASTIN("Id", ASTLiteral(1)), "IN ( id, 1 )"},
+		{ASTINLIST("Id", ASTLiteral(1), ASTLiteral(2), ASTLiteral(3)), "IN ( id, 1, 2, 3 )"},
+		{ASTINLIST("state", ASTLiteral("a"), ASTLiteral("b")), `IN ( state, "a", "b" )`},
+		{ASTOR(ASTIN("id", ASTLiteral(1)), ASTEQ("id", ASTLiteral(2))), "OR ( IN ( id, 1 ), EQ ( id, 2 ) )"},
+	}
+
+	for i, tt := range tests {
+		got := tt.f.ToString()
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - function wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
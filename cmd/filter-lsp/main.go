@@ -0,0 +1,55 @@
+package main
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Language Server for the Filter Language (LSP over stdio)
+//
+// Usage: filter-lsp [-schema schema.json] [-keys filter,where]
+//
+// The schema file and the keys of filters in YAML and JSON documents can
+// also be given by the client, as the "schema" and "keys" initialization
+// options.
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/objectvault/filter-parser/lsp"
+	"github.com/objectvault/filter-parser/schema"
+)
+
+func main() {
+	schemaFile := flag.String("schema", "", "schema file (JSON) used for diagnostics, completion and hover")
+	keys := flag.String("keys", "", "comma separated keys of filters in YAML and JSON documents (default \"filter\")")
+	flag.Parse()
+
+	s := lsp.NewServer(os.Stdin, os.Stdout)
+	s.Log = os.Stderr
+
+	// Filter Keys Given?
+	if *keys != "" { // YES
+		s.Keys = strings.Split(*keys, ",")
+	}
+
+	// Schema File Given?
+	if *schemaFile != "" { // YES: Load it
+		sc, e := schema.LoadFile(*schemaFile)
+		if e != nil {
+			fmt.Fprintln(os.Stderr, e.ToString())
+			os.Exit(2)
+		}
+		s.Schema = sc
+	}
+
+	os.Exit(s.Run())
+}
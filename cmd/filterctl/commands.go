@@ -0,0 +1,182 @@
+package main
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/printer"
+	"github.com/objectvault/filter-parser/token"
+)
+
+// Command Context (Flags, Input and Pipeline)
+type context struct {
+	fs         *flag.FlagSet
+	p          *pipeline
+	file       string
+	schemaFile string
+	stdin      io.Reader
+	stdout     io.Writer
+	stderr     io.Writer
+}
+
+func newContext(name string, stdin io.Reader, stdout io.Writer, stderr io.Writer) *context {
+	c := &context{fs: flag.NewFlagSet(name, flag.ContinueOnError), p: newPipeline(), stdin: stdin, stdout: stdout, stderr: stderr}
+	c.fs.SetOutput(stderr)
+	c.fs.StringVar(&c.file, "f", "", "read the filter from file (- for stdin)")
+	c.fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: filterctl %s [options] [filter | -f file | -]\n", name)
+		c.fs.PrintDefaults()
+	}
+	return c
+}
+
+// Parse Flags, Load Schema and Read Input
+func (c *context) setup(args []string) (string, int) {
+	if err := c.fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return "", EXIT_OK
+		}
+		return "", EXIT_USAGE
+	}
+
+	if c.schemaFile != "" {
+		if e := c.p.loadSchema(c.schemaFile); e != nil {
+			return "", c.fail(e)
+		}
+	}
+
+	text, e := input(c.fs, c.file, c.stdin)
+	if e != nil {
+		return "", c.fail(e)
+	}
+	return text, -1
+}
+
+func (c *context) fail(e *StageError) int {
+	fmt.Fprintln(c.stderr, e.Message)
+	return e.Code
+}
+
+func cmdLex(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	c := newContext("lex", stdin, stdout, stderr)
+	text, code := c.setup(args)
+	if code >= 0 {
+		return code
+	}
+
+	// Illegal Tokens are Parse Errors
+	if !printTokens(stdout, "", text) {
+		return EXIT_PARSE
+	}
+	return EXIT_OK
+}
+
+// Print Tokens (returns false if there are ILLEGAL tokens)
+func printTokens(w io.Writer, indent string, text string) bool {
+	valid := true
+	l := lexer.NewLexer(text)
+	for tok := l.NextToken(); tok.Type != token.EOL; tok = l.NextToken() {
+		start, end := l.Span()
+		fmt.Fprintf(w, "%s%d:%d\t%s\t%q\n", indent, start, end, tok.Type, tok.Literal)
+
+		if tok.Type == token.ILLEGAL {
+			valid = false
+		}
+	}
+	return valid
+}
+
+func cmdParse(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	c := newContext("parse", stdin, stdout, stderr)
+	asJSON := c.fs.Bool("json", false, "print the AST as JSON")
+	c.fs.BoolVar(&c.p.Query, "query", false, "input is a query (fields, group, having, sort, limit clauses)")
+	text, code := c.setup(args)
+	if code >= 0 {
+		return code
+	}
+
+	n, e := c.p.parse(text)
+	if e != nil {
+		return c.fail(e)
+	}
+
+	if *asJSON {
+		b, _ := json.MarshalIndent(nodeJSON(n), "", "  ")
+		fmt.Fprintln(stdout, string(b))
+	} else {
+		fmt.Fprintln(stdout, n.ToString())
+	}
+	return EXIT_OK
+}
+
+func cmdCheck(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	c := newContext("check", stdin, stdout, stderr)
+	pipelineFlags(c.fs, c.p, &c.schemaFile)
+	text, code := c.setup(args)
+	if code >= 0 {
+		return code
+	}
+
+	n, e := c.p.parse(text)
+	if e == nil {
+		e = c.p.check(n)
+	}
+
+	if e != nil {
+		return c.fail(e)
+	}
+
+	fmt.Fprintln(stdout, "OK")
+	return EXIT_OK
+}
+
+func cmdFmt(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	c := newContext("fmt", stdin, stdout, stderr)
+	width := c.fs.Int("width", printer.DEFAULT_WIDTH, "maximum line width (0 - single line)")
+	text, code := c.setup(args)
+	if code >= 0 {
+		return code
+	}
+
+	n, e := c.p.parse(text)
+	if e != nil {
+		return c.fail(e)
+	}
+
+	pr := printer.NewPrinter()
+	pr.Width = *width
+	fmt.Fprintln(stdout, pr.Print(n.(*ast.Filter)))
+	return EXIT_OK
+}
+
+func cmdTranspile(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	c := newContext("transpile", stdin, stdout, stderr)
+	pipelineFlags(c.fs, c.p, &c.schemaFile)
+	transpilerFlags(c.fs, c.p)
+	text, code := c.setup(args)
+	if code >= 0 {
+		return code
+	}
+
+	out, e := c.p.run(text)
+	if e != nil {
+		return c.fail(e)
+	}
+
+	fmt.Fprintln(stdout, out)
+	return EXIT_OK
+}
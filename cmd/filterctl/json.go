@@ -0,0 +1,88 @@
+package main
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/printer"
+	"github.com/objectvault/filter-parser/token"
+)
+
+// AST as JSON Friendly Maps
+func nodeJSON(n interface{}) interface{} {
+	switch a := n.(type) {
+	case *ast.Filter:
+		if a == nil || a.F == nil {
+			return nil
+		}
+		return nodeJSON(a.F)
+	case *ast.Function:
+		params := make([]interface{}, 0, len(a.Parameters))
+		for _, pi := range a.Parameters {
+			params = append(params, nodeJSON(pi))
+		}
+		return map[string]interface{}{"function": a.Name.Literal, "parameters": params}
+	case *ast.Value:
+		return map[string]interface{}{"type": a.V.Type, "value": a.V.Literal, "text": printer.Value(a)}
+	case *ast.Query:
+		return queryJSON(a)
+	}
+
+	return map[string]interface{}{"error": n.(ast.Node).ToString()}
+}
+
+func queryJSON(q *ast.Query) interface{} {
+	m := map[string]interface{}{"filter": nodeJSON(q.Filter)}
+
+	if q.Fields != nil {
+		aggregates := make([]interface{}, 0, len(q.Fields.Aggregates))
+		for _, a := range q.Fields.Aggregates {
+			aggregates = append(aggregates, map[string]interface{}{"function": a.Function.Literal, "field": optionalLiteral(a.Field), "alias": a.Alias()})
+		}
+		m["fields"] = map[string]interface{}{"fields": literals(q.Fields.Fields), "aggregates": aggregates}
+	}
+
+	if q.Group != nil {
+		m["group"] = literals(q.Group.Fields)
+	}
+
+	if q.Having != nil {
+		m["having"] = nodeJSON(q.Having)
+	}
+
+	if q.Sort != nil {
+		fields := make([]interface{}, 0, len(q.Sort.Fields))
+		for _, sf := range q.Sort.Fields {
+			fields = append(fields, map[string]interface{}{"field": sf.Field.Literal, "descending": sf.Descending})
+		}
+		m["sort"] = fields
+	}
+
+	if q.Limit != nil {
+		m["limit"] = map[string]interface{}{"count": q.Limit.Count.Literal, "offset": optionalLiteral(q.Limit.Offset)}
+	}
+	return m
+}
+
+func literals(tokens []token.Token) []string {
+	l := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		l = append(l, t.Literal)
+	}
+	return l
+}
+
+func optionalLiteral(t *token.Token) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Literal
+}
@@ -0,0 +1,83 @@
+package main
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Command Line Tool for the Filter Language
+//
+// Usage: filterctl <command> [options] [filter | -f file | -]
+//
+// The filter is read from the arguments, a file (-f) or stdin.
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit Codes (one per Stage)
+const (
+	EXIT_OK        = 0
+	EXIT_PARSE     = 1 // Lexer or Parser Error
+	EXIT_SYNTAX    = 2 // Syntax Checker Error
+	EXIT_TRANSPILE = 3 // Transpiler Error
+	EXIT_USAGE     = 4 // Invalid Arguments, Unreadable Input or Schema
+)
+
+const usage = `usage: filterctl <command> [options] [filter | -f file | -]
+
+commands:
+  lex        print the tokens
+  parse      print the AST (-json for JSON)
+  check      parse and run the syntax checker (-schema file)
+  fmt        print the filter in canonical form
+  transpile  check and transpile to SQL (-target mysql or -target postgres)
+  repl       interactive session (tokens, AST, check and transpiled output)
+
+exit codes:
+  0 success, 1 parse error, 2 syntax error, 3 transpile error, 4 usage error
+
+run "filterctl <command> -h" for the command options
+`
+
+type command = func(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int
+
+var commands = map[string]command{
+	"lex":       cmdLex,
+	"parse":     cmdParse,
+	"check":     cmdCheck,
+	"fmt":       cmdFmt,
+	"transpile": cmdTranspile,
+	"repl":      cmdRepl,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return EXIT_USAGE
+	}
+
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(stdout, usage)
+		return EXIT_OK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "filterctl: unknown command [%s]\n\n%s", args[0], usage)
+		return EXIT_USAGE
+	}
+
+	return cmd(args[1:], stdin, stdout, stderr)
+}
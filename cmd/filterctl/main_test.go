@@ -0,0 +1,134 @@
+package main
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Run filterctl with Arguments and Stdin (returns Exit Code, Stdout and Stderr)
+func runCmd(args []string, stdin string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// Write Test File (returns its Path)
+func writeFile(t *testing.T, name string, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("writing [%s]: %s", path, err.Error())
+	}
+	return path
+}
+
+const testSchema = `{
+  "fields": [
+    { "name": "state", "type": "string" },
+    { "name": "n", "type": "int" }
+  ]
+}`
+
+func TestRun(t *testing.T) {
+	schemaFile := writeFile(t, "schema.json", testSchema)
+	filterFile := writeFile(t, "filter.txt", `eq(state, "a")`)
+
+	tests := []struct {
+		args           []string
+		stdin          string
+		expectedCode   int
+		expectedStdout string // Substring ("" - Empty)
+		expectedStderr string // Substring ("" - Empty)
+	}{
+		// Usage
+		{[]string{}, "", EXIT_USAGE, "", "usage: filterctl"},
+		{[]string{"help"}, "", EXIT_OK, "usage: filterctl", ""},
+		{[]string{"zz"}, "", EXIT_USAGE, "", "unknown command [zz]"},
+		{[]string{"parse", "-zz", "eq(a, 1)"}, "", EXIT_USAGE, "", "flag provided but not defined: -zz"},
+		{[]string{"parse", "-h"}, "", EXIT_OK, "", "usage: filterctl parse"},
+
+		// lex
+		{[]string{"lex", "eq(a, 1)"}, "", EXIT_OK, "0:2\tIDENT\t\"eq\"\n2:3\t(\t\"(\"\n", ""},
+		{[]string{"lex", "eq(a, #)"}, "", EXIT_PARSE, "6:7\tILLEGAL\t\"#\"", ""},
+
+		// parse
+		{[]string{"parse", "eq(a,", "1)"}, "", EXIT_OK, "eq ( a, 1 )", ""},
+		{[]string{"parse", "-json", "eq(a, 1)"}, "", EXIT_OK, `"function": "eq"`, ""},
+		{[]string{"parse", "-query", "fields(a) limit(5)"}, "", EXIT_OK, "fields ( a ) limit ( 5 )", ""},
+		{[]string{"parse", "-"}, "eq(a, 1)", EXIT_OK, "eq ( a, 1 )", ""},
+		{[]string{"parse"}, "eq(a, 1)", EXIT_OK, "eq ( a, 1 )", ""},
+		{[]string{"parse", "-f", filterFile}, "", EXIT_OK, `eq ( state, "a" )`, ""},
+		{[]string{"parse", "-f", filterFile + ".zz"}, "", EXIT_USAGE, "", "no such file"},
+		{[]string{"parse", "eq(a"}, "", EXIT_PARSE, "", "PARSE ERROR:"},
+
+		// check
+		{[]string{"check", "eq(a, 1)"}, "", EXIT_OK, "OK", ""},
+		{[]string{"check", "-schema", schemaFile, `eq(state, "a")`}, "", EXIT_OK, "OK", ""},
+		{[]string{"check", "-schema", schemaFile, "eq(a, 1)"}, "", EXIT_SYNTAX, "", "SYNTAX ERROR: Function [EQ] Field [a] is not recognized"},
+		{[]string{"check", "-schema", schemaFile + ".zz", "eq(a, 1)"}, "", EXIT_USAGE, "", "no such file"},
+		{[]string{"check", "eq(a, $n)"}, "", EXIT_SYNTAX, "", "SYNTAX ERROR:"},
+		{[]string{"check", "-param", "n=int", "eq(a, $n)"}, "", EXIT_OK, "OK", ""},
+		{[]string{"check", "-param", "n=date", "eq(a, $n)"}, "", EXIT_USAGE, "", "Invalid parameter type [date]"},
+		{[]string{"check", "eq(a"}, "", EXIT_PARSE, "", "PARSE ERROR:"},
+
+		// fmt
+		{[]string{"fmt", "and(eq(a,1),gt(b,2))"}, "", EXIT_OK, "and(eq(a, 1), gt(b, 2))\n", ""},
+		{[]string{"fmt", "-width", "10", "and(eq(a,1),gt(b,2))"}, "", EXIT_OK, "and(\n", ""},
+		{[]string{"fmt", "eq(a,"}, "", EXIT_PARSE, "", "PARSE ERROR:"},
+
+		// transpile
+		{[]string{"transpile", `eq(state, "a")`}, "", EXIT_OK, `state = "a"`, ""},
+		{[]string{"transpile", "-map", "state=t.state", `eq(state, "a")`}, "", EXIT_OK, `t.state = "a"`, ""},
+		{[]string{"transpile", "-map", "state", `eq(state, "a")`}, "", EXIT_USAGE, "", "Invalid mapping [state]"},
+		{[]string{"transpile", "-map", "state=t.state", "eq(n, 1)"}, "", EXIT_TRANSPILE, "", "TRANSPILER ERROR: Invalid Field [n]"},
+		{[]string{"transpile", "-param", "n=int", "eq(n, $n)"}, "", EXIT_TRANSPILE, "", "TRANSPILER ERROR: Unbound Parameter [$n]"},
+		{[]string{"transpile", "-target", "pg", "eq(a, 1)"}, "", EXIT_USAGE, "", "Target [pg] not supported (supported: mysql, postgres)"},
+		{[]string{"transpile", "-target", "postgres", `eq(state, "it's")`}, "", EXIT_OK, `state = 'it''s'`, ""},
+		{[]string{"transpile", "-target", "postgres", "-query", "fields(a, count()) group(a) having(gt(count, 1))"}, "", EXIT_OK, `SELECT a, COUNT(*) AS "count" FROM t GROUP BY a HAVING COUNT(*) > 1`, ""},
+		{[]string{"transpile", "-query", "-table", "items", "fields(a) eq(a, 1) limit(5)"}, "", EXIT_OK, "SELECT a FROM items WHERE a = 1 LIMIT 5", ""},
+		{[]string{"transpile", "-schema", schemaFile, "eq(a, 1)"}, "", EXIT_SYNTAX, "", "SYNTAX ERROR:"},
+		{[]string{"transpile", "eq(a"}, "", EXIT_PARSE, "", "PARSE ERROR:"},
+	}
+
+	for i, tt := range tests {
+		code, stdout, stderr := runCmd(tt.args, tt.stdin)
+
+		if code != tt.expectedCode {
+			t.Fatalf("tests[%d] %v - exit code wrong. expected=%d, got=%d (stderr=%q)", i, tt.args, tt.expectedCode, code, stderr)
+		}
+		if (tt.expectedStdout == "" && stdout != "") || !strings.Contains(stdout, tt.expectedStdout) {
+			t.Fatalf("tests[%d] %v - stdout wrong. expected=%q, got=%q", i, tt.args, tt.expectedStdout, stdout)
+		}
+		if (tt.expectedStderr == "" && stderr != "") || !strings.Contains(stderr, tt.expectedStderr) {
+			t.Fatalf("tests[%d] %v - stderr wrong. expected=%q, got=%q", i, tt.args, tt.expectedStderr, stderr)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	// Every Command is Documented
+	for name := range commands {
+		if !strings.Contains(usage, "\n  "+name+" ") {
+			t.Fatalf("command [%s] missing from usage", name)
+		}
+	}
+
+	// Only the Registered Targets are Documented
+	for _, name := range targetNames() {
+		if !strings.Contains(usage, "-target "+name) {
+			t.Fatalf("target [%s] missing from usage", name)
+		}
+	}
+}
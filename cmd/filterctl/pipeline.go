@@ -0,0 +1,284 @@
+package main
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+	"github.com/objectvault/filter-parser/schema"
+	"github.com/objectvault/filter-parser/syntax"
+	"github.com/objectvault/filter-parser/token"
+	"github.com/objectvault/filter-parser/transpiler"
+)
+
+/*
+  PIPELINE
+  The same stages a service runs: parser => syntax checker => transpiler.
+  Every stage failure carries the exit code of the stage.
+*/
+
+// Stage Error Object
+type StageError struct {
+	Code    int // Exit Code
+	Message string
+}
+
+func (e *StageError) ToString() string {
+	return e.Message
+}
+
+// Transpile Function for a Target (table is only used by queries)
+type TTranspile = func(n ast.Node, mapper transpiler.TMapIdentityToField, table string) (string, *StageError)
+
+var targets = map[string]TTranspile{
+	"mysql":    transpileMysql,
+	"postgres": transpilePostgres,
+}
+
+// Pipeline Configuration
+type pipeline struct {
+	Query  bool                       // Parse Queries (fields, sort, limit...) instead of Filters
+	Schema *schema.Schema             // OPTIONAL: Known Fields
+	Fields map[string]string          // OPTIONAL: Field to Column Map (nil - Field Names are Columns)
+	Params map[string]token.TokenType // Declared Bind Parameters
+	Target string                     // Transpiler Target
+	Table  string                     // Table Name for Queries
+}
+
+func newPipeline() *pipeline {
+	p := &pipeline{Target: "mysql", Table: "t", Params: make(map[string]token.TokenType)}
+	return p
+}
+
+func (p *pipeline) parse(text string) (ast.Node, *StageError) {
+	ps := parser.NewParser(lexer.NewLexer(text))
+
+	var r interface{}
+	if p.Query {
+		r = ps.ParseQuery()
+	} else {
+		r = ps.ParseFilter()
+	}
+
+	if e, ok := r.(*ast.ParseError); ok {
+		return nil, &StageError{Code: EXIT_PARSE, Message: "PARSE ERROR: " + strings.TrimSpace(e.Message)}
+	}
+	return r.(ast.Node), nil
+}
+
+func (p *pipeline) check(n ast.Node) *StageError {
+	c := syntax.NewSyntaxChecker(n)
+	c.Schema = p.Schema
+	c.Params = p.Params
+
+	if e := c.Verify(); e != nil {
+		return &StageError{Code: EXIT_SYNTAX, Message: "SYNTAX ERROR: " + e.Message}
+	}
+	return nil
+}
+
+func (p *pipeline) transpile(n ast.Node) (string, *StageError) {
+	t, ok := targets[p.Target]
+	if !ok {
+		return "", &StageError{Code: EXIT_USAGE, Message: fmt.Sprintf("Target [%s] not supported (supported: %s)", p.Target, strings.Join(targetNames(), ", "))}
+	}
+
+	return t(n, p.mapper(), p.Table)
+}
+
+// Parse, Check and Transpile
+func (p *pipeline) run(text string) (string, *StageError) {
+	n, e := p.parse(text)
+	if e != nil {
+		return "", e
+	}
+
+	if e = p.check(n); e != nil {
+		return "", e
+	}
+
+	return p.transpile(n)
+}
+
+// Field Mapper (Unmapped Fields are Invalid, as in a Service)
+func (p *pipeline) mapper() transpiler.TMapIdentityToField {
+	if p.Fields == nil {
+		return nil
+	}
+
+	return func(field string) string {
+		return p.Fields[field]
+	}
+}
+
+func (p *pipeline) loadSchema(path string) *StageError {
+	s, e := schema.LoadFile(path)
+	if e != nil {
+		return &StageError{Code: EXIT_USAGE, Message: e.ToString()}
+	}
+
+	p.Schema = s
+	return nil
+}
+
+// Add "field=column" Mapping
+func (p *pipeline) addMapping(mapping string) *StageError {
+	i := strings.Index(mapping, "=")
+	if i <= 0 || i == len(mapping)-1 {
+		return &StageError{Code: EXIT_USAGE, Message: fmt.Sprintf("Invalid mapping [%s] (expecting field=column)", mapping)}
+	}
+
+	if p.Fields == nil {
+		p.Fields = make(map[string]string)
+	}
+	p.Fields[strings.ToLower(mapping[:i])] = mapping[i+1:]
+	return nil
+}
+
+// Declare "name=type" Bind Parameter
+func (p *pipeline) addParam(param string) *StageError {
+	i := strings.Index(param, "=")
+	if i <= 0 {
+		return &StageError{Code: EXIT_USAGE, Message: fmt.Sprintf("Invalid parameter [%s] (expecting name=type)", param)}
+	}
+
+	t := token.TokenType(strings.ToUpper(param[i+1:]))
+	if t != token.STRING && t != token.INT && t != token.NUMBER {
+		return &StageError{Code: EXIT_USAGE, Message: fmt.Sprintf("Invalid parameter type [%s] (expecting string, int or number)", param[i+1:])}
+	}
+
+	p.Params[strings.TrimPrefix(param[:i], "$")] = t
+	return nil
+}
+
+func transpileMysql(n ast.Node, mapper transpiler.TMapIdentityToField, table string) (string, *StageError) {
+	var r interface{}
+	switch a := n.(type) {
+	case *ast.Query:
+		r = transpiler.NewTranspileToMysqlQuery(a, mapper).Transpile()
+	case *ast.Filter:
+		r = transpiler.NewTranspileToMysqlWhere(a, mapper).Transpile()
+	}
+
+	switch v := r.(type) {
+	case string:
+		return v, nil
+	case *transpiler.MysqlQuery:
+		return v.SQL(table), nil
+	case *transpiler.TranspilerError:
+		return "", &StageError{Code: EXIT_TRANSPILE, Message: "TRANSPILER ERROR: " + v.Message}
+	}
+	return "", &StageError{Code: EXIT_TRANSPILE, Message: "TRANSPILER ERROR: unexpected result"}
+}
+
+func transpilePostgres(n ast.Node, mapper transpiler.TMapIdentityToField, table string) (string, *StageError) {
+	var r interface{}
+	switch a := n.(type) {
+	case *ast.Query:
+		r = transpiler.NewTranspileToPostgresQuery(a, mapper).Transpile()
+	case *ast.Filter:
+		r = transpiler.NewTranspileToPostgresWhere(a, mapper).Transpile()
+	}
+
+	switch v := r.(type) {
+	case string:
+		return v, nil
+	case *transpiler.PostgresQuery:
+		return v.SQL(table), nil
+	case *transpiler.TranspilerError:
+		return "", &StageError{Code: EXIT_TRANSPILE, Message: "TRANSPILER ERROR: " + v.Message}
+	}
+	return "", &StageError{Code: EXIT_TRANSPILE, Message: "TRANSPILER ERROR: unexpected result"}
+}
+
+func targetNames() []string {
+	l := make([]string, 0, len(targets))
+	for name := range targets {
+		l = append(l, name)
+	}
+
+	sort.Strings(l)
+	return l
+}
+
+// Repeatable "-map field=column" Flag
+type mappings struct {
+	p *pipeline
+}
+
+func (m *mappings) String() string {
+	return ""
+}
+
+func (m *mappings) Set(value string) error {
+	if e := m.p.addMapping(value); e != nil {
+		return fmt.Errorf("%s", e.Message)
+	}
+	return nil
+}
+
+// Repeatable "-param name=type" Flag
+type params struct {
+	p *pipeline
+}
+
+func (ps *params) String() string {
+	return ""
+}
+
+func (ps *params) Set(value string) error {
+	if e := ps.p.addParam(value); e != nil {
+		return fmt.Errorf("%s", e.Message)
+	}
+	return nil
+}
+
+// Common Flags
+func pipelineFlags(fs *flag.FlagSet, p *pipeline, schemaFile *string) {
+	fs.BoolVar(&p.Query, "query", false, "input is a query (fields, group, having, sort, limit clauses)")
+	fs.StringVar(schemaFile, "schema", "", "schema file (JSON)")
+	fs.Var(&params{p: p}, "param", "declare bind parameter, name=type (repeatable)")
+}
+
+// Transpiler Flags
+func transpilerFlags(fs *flag.FlagSet, p *pipeline) {
+	fs.StringVar(&p.Target, "target", p.Target, "transpiler target (mysql or postgres)")
+	fs.StringVar(&p.Table, "table", p.Table, "table name for queries")
+	fs.Var(&mappings{p: p}, "map", "map field to column, field=column (repeatable, unmapped fields are invalid)")
+}
+
+// Read Filter Text from Arguments, File or Stdin
+func input(fs *flag.FlagSet, file string, stdin io.Reader) (string, *StageError) {
+	var data []byte
+	var err error
+
+	switch {
+	case file != "" && file != "-":
+		data, err = ioutil.ReadFile(file)
+	case file == "-" || fs.NArg() == 0 || (fs.NArg() == 1 && fs.Arg(0) == "-"):
+		data, err = ioutil.ReadAll(stdin)
+	default:
+		return strings.Join(fs.Args(), " "), nil
+	}
+
+	if err != nil {
+		return "", &StageError{Code: EXIT_USAGE, Message: err.Error()}
+	}
+	return string(data), nil
+}
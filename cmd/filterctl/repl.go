@@ -0,0 +1,323 @@
+package main
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+  REPL
+  Every line is either a command (starts with ':'), a history reference
+  (!n re-runs entry n) or a filter, which is run through the pipeline
+  showing every stage: tokens, AST, syntax check and transpiled output.
+
+  Only filters are kept in the history (the last HISTORY_SIZE entries).
+*/
+
+// Maximum History Entries (Memory and File)
+const HISTORY_SIZE = 500
+
+const replHelp = `commands:
+  :schema <file>      load schema, the rest of the line is the file (:schema off to drop it)
+  :map                show field mappings
+  :map field=column   map field to column (unmapped fields are invalid)
+  :map clear          drop all mappings (field names are columns)
+  :param name=type    declare bind parameter (:param clear to drop all)
+  :target <name>      set transpiler target
+  :table <name>       set table name for queries
+  :query on|off       parse input as query / filter
+  :tokens on|off      show / hide tokens
+  :history            list history (!n re-runs entry n)
+  :help               this help
+  :quit               exit
+`
+
+type repl struct {
+	p       *pipeline
+	tokens  bool     // Show Tokens
+	history []string // Entered Filters (Oldest First)
+	file    string   // OPTIONAL: History File
+	out     io.Writer
+}
+
+func cmdRepl(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	c := newContext("repl", stdin, stdout, stderr)
+	pipelineFlags(c.fs, c.p, &c.schemaFile)
+	transpilerFlags(c.fs, c.p)
+	history := c.fs.String("history", defaultHistoryFile(), "history file (empty - no history file)")
+
+	if err := c.fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return EXIT_OK
+		}
+		return EXIT_USAGE
+	}
+
+	if c.schemaFile != "" {
+		if e := c.p.loadSchema(c.schemaFile); e != nil {
+			return c.fail(e)
+		}
+	}
+
+	r := &repl{p: c.p, tokens: true, file: *history, out: stdout}
+	r.load()
+
+	fmt.Fprintln(stdout, "filterctl repl (:help for commands)")
+	scanner := bufio.NewScanner(stdin)
+	for {
+		fmt.Fprint(stdout, r.prompt())
+		if !scanner.Scan() {
+			fmt.Fprintln(stdout)
+			return EXIT_OK
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// History Reference?
+		if strings.HasPrefix(line, "!") { // YES: Replace with Entry
+			n, err := strconv.Atoi(line[1:])
+			if err != nil || n < 1 || n > len(r.history) {
+				fmt.Fprintf(stdout, "no history entry [%s]\n", line[1:])
+				continue
+			}
+
+			line = r.history[n-1]
+			fmt.Fprintln(stdout, line)
+		}
+
+		if strings.HasPrefix(line, ":") {
+			if !r.command(line[1:]) {
+				return EXIT_OK
+			}
+			continue
+		}
+
+		r.remember(line)
+		r.evaluate(line)
+	}
+}
+
+func (r *repl) prompt() string {
+	if r.p.Query {
+		return fmt.Sprintf("%s query> ", r.p.Target)
+	}
+	return fmt.Sprintf("%s filter> ", r.p.Target)
+}
+
+// Run Filter through Pipeline, Showing every Stage
+func (r *repl) evaluate(text string) {
+	if r.tokens {
+		fmt.Fprintln(r.out, "TOKENS")
+		printTokens(r.out, "  ", text)
+	}
+
+	n, e := r.p.parse(text)
+	if e != nil {
+		fmt.Fprintln(r.out, e.Message)
+		return
+	}
+	fmt.Fprintf(r.out, "AST\n  %s\n", n.ToString())
+
+	if e = r.p.check(n); e != nil {
+		fmt.Fprintln(r.out, e.Message)
+		return
+	}
+	fmt.Fprintln(r.out, "CHECK\n  OK")
+
+	out, e := r.p.transpile(n)
+	if e != nil {
+		fmt.Fprintln(r.out, e.Message)
+		return
+	}
+	fmt.Fprintf(r.out, "%s\n  %s\n", strings.ToUpper(r.p.Target), out)
+}
+
+// Execute Command (returns false to exit)
+func (r *repl) command(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		fmt.Fprint(r.out, replHelp)
+		return true
+	}
+
+	arg := ""
+	if len(fields) > 1 {
+		arg = fields[1]
+	}
+
+	// Rest of the Line (i.e. File Names with Spaces)
+	rest := strings.TrimSpace(strings.TrimSpace(line)[len(fields[0]):])
+
+	var e *StageError
+	switch fields[0] {
+	case "quit", "exit", "q":
+		return false
+	case "help", "h":
+		fmt.Fprint(r.out, replHelp)
+	case "schema":
+		switch rest {
+		case "":
+			fmt.Fprintln(r.out, "usage: :schema <file> | off")
+		case "off":
+			r.p.Schema = nil
+		default:
+			e = r.p.loadSchema(rest)
+		}
+	case "map":
+		switch arg {
+		case "":
+			r.showMappings()
+		case "clear":
+			r.p.Fields = nil
+		default:
+			e = r.p.addMapping(arg)
+		}
+	case "param":
+		switch arg {
+		case "":
+			for name, t := range r.p.Params {
+				fmt.Fprintf(r.out, "  $%s\t%s\n", name, t)
+			}
+		case "clear":
+			r.p.Params = newPipeline().Params
+		default:
+			e = r.p.addParam(arg)
+		}
+	case "target":
+		if _, ok := targets[arg]; !ok {
+			fmt.Fprintf(r.out, "target [%s] not supported (supported: %s)\n", arg, strings.Join(targetNames(), ", "))
+			break
+		}
+		r.p.Target = arg
+	case "table":
+		if arg == "" {
+			fmt.Fprintln(r.out, "usage: :table <name>")
+			break
+		}
+		r.p.Table = arg
+	case "query", "tokens":
+		if rest != "on" && rest != "off" {
+			fmt.Fprintf(r.out, "usage: :%s on|off\n", fields[0])
+			break
+		}
+
+		if fields[0] == "query" {
+			r.p.Query = rest == "on"
+		} else {
+			r.tokens = rest == "on"
+		}
+	case "history":
+		for i, h := range r.history {
+			fmt.Fprintf(r.out, "%4d  %s\n", i+1, h)
+		}
+	default:
+		fmt.Fprintf(r.out, "unknown command [:%s] (:help for commands)\n", fields[0])
+	}
+
+	if e != nil {
+		fmt.Fprintln(r.out, e.Message)
+	}
+	return true
+}
+
+func (r *repl) showMappings() {
+	if r.p.Fields == nil {
+		fmt.Fprintln(r.out, "  no mappings (field names are columns)")
+		return
+	}
+
+	names := make([]string, 0, len(r.p.Fields))
+	for name := range r.p.Fields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(r.out, "  %s => %s\n", name, r.p.Fields[name])
+	}
+}
+
+// Load History File
+func (r *repl) load() {
+	if r.file == "" {
+		return
+	}
+
+	data, err := ioutil.ReadFile(r.file)
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			r.history = append(r.history, line)
+		}
+	}
+
+	// Keep Last Entries
+	if len(r.history) > HISTORY_SIZE {
+		r.history = r.history[len(r.history)-HISTORY_SIZE:]
+	}
+}
+
+// Add Entry to History (and History File)
+func (r *repl) remember(line string) {
+	// Same as Last Entry?
+	if len(r.history) > 0 && r.history[len(r.history)-1] == line { // YES: Skip
+		return
+	}
+
+	r.history = append(r.history, line)
+
+	// History Full?
+	trimmed := len(r.history) > HISTORY_SIZE
+	if trimmed { // YES: Drop Oldest Entry
+		r.history = r.history[1:]
+	}
+
+	if r.file == "" {
+		return
+	}
+
+	// History File has Entries that were Dropped?
+	if trimmed { // YES: Rewrite it
+		ioutil.WriteFile(r.file, []byte(strings.Join(r.history, "\n")+"\n"), 0600)
+		return
+	}
+
+	f, err := os.OpenFile(r.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".filterctl_history")
+}
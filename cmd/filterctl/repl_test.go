@@ -0,0 +1,123 @@
+package main
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Run REPL Session (Lines of Input) without a History File
+func runRepl(args []string, lines ...string) (int, string) {
+	code, stdout, _ := runCmd(append([]string{"repl", "-history", ""}, args...), strings.Join(lines, "\n")+"\n")
+	return code, stdout
+}
+
+func TestRepl(t *testing.T) {
+	schemaFile := writeFile(t, "my schema.json", testSchema)
+
+	tests := []struct {
+		lines    []string
+		expected []string // Output Substrings (in Order)
+		missing  string   // OPTIONAL: Text not in Output
+	}{
+		{[]string{`eq(a, 1)`}, []string{"mysql filter> ", "TOKENS\n  0:2\tIDENT\t\"eq\"", "AST\n  eq ( a, 1 )", "CHECK\n  OK", "MYSQL\n  a = 1"}, ""},
+		{[]string{":tokens off", `eq(a, 1)`}, []string{"AST\n  eq ( a, 1 )"}, "TOKENS"},
+		{[]string{":tokens", ":tokens yes", `eq(a, 1)`}, []string{"usage: :tokens on|off", "usage: :tokens on|off", "TOKENS"}, ""},
+		{[]string{":query on", "fields(a) limit(5)", ":query off"}, []string{"mysql query> ", "SELECT a FROM t LIMIT 5", "mysql filter> "}, ""},
+		{[]string{":query", ":query maybe"}, []string{"usage: :query on|off", "usage: :query on|off"}, "query> "},
+		{[]string{":schema " + schemaFile, `eq(a, 1)`}, []string{"SYNTAX ERROR: Function [EQ] Field [a] is not recognized"}, "no such file"},
+		{[]string{":schema " + schemaFile, ":schema off", `eq(a, 1)`}, []string{"CHECK\n  OK"}, ""},
+		{[]string{":schema", ":schema /no/such file.json"}, []string{"usage: :schema <file> | off", "Schema [/no/such file.json]"}, ""},
+		{[]string{":map", ":map a=t.a", ":map", `eq(a, 1)`, ":map clear", ":map"}, []string{"no mappings", "a => t.a", "MYSQL\n  t.a = 1", "no mappings"}, ""},
+		{[]string{":map a", ":param n=date"}, []string{"Invalid mapping [a]", "Invalid parameter type [date]"}, ""},
+		{[]string{":param n=int", ":param", `eq(a, $n)`}, []string{"$n\tINT", "CHECK\n  OK", "TRANSPILER ERROR: Unbound Parameter [$n]"}, ""},
+		{[]string{":target pg", ":table", ":zz"}, []string{"target [pg] not supported (supported: mysql, postgres)", "usage: :table <name>", "unknown command [:zz]"}, ""},
+		{[]string{":target postgres", `eq(a, "x")`}, []string{"postgres filter> ", "POSTGRES\n  a = 'x'"}, ""},
+		{[]string{":help"}, []string{":schema <file>"}, ""},
+		{[]string{`eq(a`}, []string{"PARSE ERROR:"}, "AST"},
+		{[]string{":quit", `eq(a, 1)`}, []string{"filterctl repl"}, "AST"},
+	}
+
+	for i, tt := range tests {
+		code, out := runRepl(nil, tt.lines...)
+		if code != EXIT_OK {
+			t.Fatalf("tests[%d] - exit code wrong. expected=%d, got=%d", i, EXIT_OK, code)
+		}
+
+		rest := out
+		for _, expected := range tt.expected {
+			j := strings.Index(rest, expected)
+			if j < 0 {
+				t.Fatalf("tests[%d] - output wrong. expected=%q, got=%q", i, expected, out)
+			}
+			rest = rest[j+len(expected):]
+		}
+
+		if tt.missing != "" && strings.Contains(out, tt.missing) {
+			t.Fatalf("tests[%d] - output wrong. unexpected=%q, got=%q", i, tt.missing, out)
+		}
+	}
+}
+
+func TestReplHistory(t *testing.T) {
+	// Commands are not Kept in History
+	_, out := runRepl(nil, `eq(a, 1)`, ":tokens off", `eq(a, 1)`, `gt(b, 2)`, ":history", "!2", "!3", "!zz")
+	for _, expected := range []string{"   1  eq(a, 1)\n   2  gt(b, 2)\n", "gt(b, 2)\nAST\n  gt ( b, 2 )", "no history entry [3]", "no history entry [zz]"} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("output wrong. expected=%q, got=%q", expected, out)
+		}
+	}
+	if strings.Contains(out, ":tokens off\n") {
+		t.Fatalf("command in history. got=%q", out)
+	}
+
+	// History File
+	file := filepath.Join(t.TempDir(), "history")
+	runCmd([]string{"repl", "-history", file}, "eq(a, 1)\n:tokens off\n")
+	_, out, _ = runCmd([]string{"repl", "-history", file}, "gt(b, 2)\n:history\n")
+	if !strings.Contains(out, "   1  eq(a, 1)\n   2  gt(b, 2)\n") {
+		t.Fatalf("history wrong. got=%q", out)
+	}
+
+	data, _ := ioutil.ReadFile(file)
+	if string(data) != "eq(a, 1)\ngt(b, 2)\n" {
+		t.Fatalf("history file wrong. got=%q", string(data))
+	}
+}
+
+func TestReplHistorySize(t *testing.T) {
+	// History File over the Limit
+	lines := make([]string, 0, HISTORY_SIZE+10)
+	for i := 0; i < HISTORY_SIZE+10; i++ {
+		lines = append(lines, fmt.Sprintf("eq(a, %d)", i))
+	}
+	file := writeFile(t, "history", strings.Join(lines, "\n")+"\n")
+
+	_, out, _ := runCmd([]string{"repl", "-history", file}, ":tokens off\n!1\neq(b, 1)\n")
+	if !strings.Contains(out, "eq(a, 10)\n") {
+		t.Fatalf("oldest entries not dropped. got=%q", out[:200])
+	}
+
+	data, _ := ioutil.ReadFile(file)
+	entries := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(entries) != HISTORY_SIZE {
+		t.Fatalf("history file size wrong. expected=%d, got=%d", HISTORY_SIZE, len(entries))
+	}
+
+	// Newest Entries are Kept (Oldest First)
+	if entries[0] != "eq(a, 12)" || entries[HISTORY_SIZE-2] != "eq(a, 10)" || entries[HISTORY_SIZE-1] != "eq(b, 1)" {
+		t.Fatalf("history file entries wrong. got=[%q ... %q, %q]", entries[0], entries[HISTORY_SIZE-2], entries[HISTORY_SIZE-1])
+	}
+}
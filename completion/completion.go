@@ -0,0 +1,363 @@
+package completion
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/macro"
+	"github.com/objectvault/filter-parser/schema"
+	"github.com/objectvault/filter-parser/syntax"
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  COMPLETION
+  The input, up to the cursor, is run through the lexer (no parsing, so
+  incomplete filters are fine) to find:
+  - the word being typed (identifier, @field or unterminated string)
+  - the enclosing function and the parameter the cursor is in
+
+  and(eq(state, "a|        => values for field 'state' (function EQ, parameter 2)
+  and(eq(state, "a"), |    => functions
+  and(eq(|                 => fields
+
+  NOTE: Offsets are in characters (runes) not bytes
+*/
+
+// Completion Kinds
+const (
+	KIND_FUNCTION = "function"
+	KIND_FIELD    = "field"
+	KIND_VALUE    = "value" // Known Field Value
+	KIND_TYPE     = "type"  // Expected Value Type (Hint)
+	KIND_MACRO    = "macro"
+)
+
+// Function Signature (i.e. "eq(field, value)", "" - Unknown Function)
+func Signature(name string) string {
+	return syntax.FunctionSignature(name)
+}
+
+// Completion Item
+type Item struct {
+	Label  string // Text Shown
+	Kind   string // KIND_*
+	Detail string // OPTIONAL: Signature or Type
+	Insert string // Text that Replaces the Range
+}
+
+// Completion Result
+type Result struct {
+	Start int // Replacement Range [Start, End) (in Characters)
+	End   int
+	Items []*Item
+}
+
+// Completer Object
+type Completer struct {
+	Schema *schema.Schema  // OPTIONAL: Fields (nil - No Field Completions)
+	Macros *macro.Registry // OPTIONAL: Macros (nil - No Macro Completions)
+}
+
+func NewCompleter(s *schema.Schema) *Completer {
+	c := &Completer{Schema: s}
+	return c
+}
+
+// Enclosing Function
+type frame struct {
+	name  string // Upper Case Function Name ("" - Not a Function)
+	param int    // Parameter Index (0 based)
+	field string // Field in 1st Parameter
+}
+
+// Cursor Context
+type context struct {
+	frame  *frame      // Enclosing Function (nil - Top Level)
+	word   token.Token // Word Being Typed (Type "" - None)
+	prefix string      // Word Text before the Cursor
+}
+
+func (c *Completer) Complete(input string, offset int) *Result {
+	runes := []rune(input)
+
+	// Clamp Cursor to Input
+	if offset < 0 {
+		offset = 0
+	} else if offset > len(runes) {
+		offset = len(runes)
+	}
+
+	ctx, start := c.context(string(runes[:offset]))
+	r := &Result{Start: start, End: extend(runes, offset, ctx.word.Type), Items: make([]*Item, 0)}
+
+	// Typing a Number or after a Complete Token?
+	if ctx.word.Type == token.INT || ctx.word.Type == token.NUMBER { // YES: Nothing to Suggest
+		return r
+	}
+
+	// Inside a Function?
+	class := "logical"
+	if ctx.frame != nil {
+		class = syntax.FunctionClass(ctx.frame.name)
+	}
+
+	switch {
+	case class == "macro":
+		if ctx.frame.param == 0 {
+			r.Items = c.macros(ctx)
+		}
+	case strings.HasPrefix(class, "operator"):
+		if ctx.frame.param == 0 || (class == "operator-search" && ctx.word.Type != token.STRING) {
+			r.Items = c.fields(ctx, class)
+		}
+
+		if ctx.frame.param > 0 {
+			r.Items = append(r.Items, c.values(ctx, class)...)
+		}
+	default:
+		// Top Level, Logical Operators or Unknown Functions
+		r.Items = c.functions(ctx)
+	}
+
+	return r
+}
+
+// Find the Enclosing Function and Word Being Typed
+func (c *Completer) context(input string) (*context, int) {
+	l := lexer.NewLexer(input)
+	ctx := &context{}
+	stack := make([]*frame, 0)
+
+	start := 0
+	var prev token.Token
+	for tok := l.NextToken(); tok.Type != token.EOL; tok = l.NextToken() {
+		s, e := l.Span()
+		start = s
+
+		switch tok.Type {
+		case token.LPAREN:
+			f := &frame{}
+			if prev.Type == token.IDENT {
+				f.name = strings.ToUpper(prev.Literal)
+			}
+			stack = append(stack, f)
+		case token.COMMA:
+			if len(stack) > 0 {
+				stack[len(stack)-1].param++
+			}
+		case token.RPAREN:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case token.IDENT:
+			if len(stack) > 0 && stack[len(stack)-1].param == 0 {
+				stack[len(stack)-1].field = strings.ToLower(tok.Literal)
+			}
+		}
+
+		// Does Token end at the Cursor?
+		if e == len([]rune(input)) { // YES: Possibly the Word being Typed
+			ctx.word = tok
+			ctx.prefix = string([]rune(input)[s:e])
+		}
+		prev = tok
+	}
+
+	// Is the Last Token a Word?
+	switch ctx.word.Type {
+	case token.IDENT, token.FIELD, token.INT, token.NUMBER:
+	case token.ILLEGAL:
+		// Unterminated String or a lone '@'?
+		if strings.HasPrefix(ctx.prefix, "\"") {
+			ctx.word.Type = token.STRING
+		} else if ctx.prefix == "@" {
+			ctx.word.Type = token.FIELD
+		} else {
+			ctx.word = token.Token{}
+		}
+	default:
+		ctx.word = token.Token{}
+	}
+
+	// Word being Typed?
+	if ctx.word.Type == "" { // NO: Insert at Cursor
+		ctx.prefix = ""
+		start = len([]rune(input))
+	} else if ctx.word.Type == token.IDENT && len(stack) > 0 && stack[len(stack)-1].param == 0 {
+		// Field is Incomplete
+		stack[len(stack)-1].field = ""
+	}
+
+	if len(stack) > 0 {
+		ctx.frame = stack[len(stack)-1]
+	}
+
+	return ctx, start
+}
+
+func (c *Completer) functions(ctx *context) []*Item {
+	items := make([]*Item, 0)
+
+	// Functions are only Suggested for Identifiers
+	if ctx.word.Type != "" && ctx.word.Type != token.IDENT {
+		return items
+	}
+
+	for _, name := range syntax.FUNCTIONS {
+		// Macros Registered?
+		if name == "MACRO" && c.Macros == nil { // NO: Skip
+			continue
+		}
+
+		label := strings.ToLower(name)
+		if matches(label, ctx.prefix) {
+			items = append(items, &Item{Label: label, Kind: KIND_FUNCTION, Detail: syntax.FunctionSignature(name), Insert: label + "("})
+		}
+	}
+	return items
+}
+
+func (c *Completer) macros(ctx *context) []*Item {
+	items := make([]*Item, 0)
+	if c.Macros == nil || (ctx.word.Type != "" && ctx.word.Type != token.IDENT) {
+		return items
+	}
+
+	for _, name := range c.Macros.Names() {
+		if matches(name, ctx.prefix) {
+			items = append(items, &Item{Label: name, Kind: KIND_MACRO, Insert: name})
+		}
+	}
+	return items
+}
+
+// Fields that can be used with Function
+func (c *Completer) fields(ctx *context, class string) []*Item {
+	items := make([]*Item, 0)
+	if c.Schema == nil || (ctx.word.Type != "" && ctx.word.Type != token.IDENT) {
+		return items
+	}
+
+	for _, f := range c.Schema.Fields() {
+		if !f.Allows(ctx.frame.name) || !fieldFits(f, class) {
+			continue
+		}
+
+		if matches(f.Name, ctx.prefix) {
+			items = append(items, &Item{Label: f.Name, Kind: KIND_FIELD, Detail: fieldDetail(f), Insert: f.Name})
+		}
+	}
+	return items
+}
+
+// Values for the Field in the 1st Parameter
+func (c *Completer) values(ctx *context, class string) []*Item {
+	items := make([]*Item, 0)
+
+	// Field Known?
+	var f *schema.Field
+	if c.Schema != nil && ctx.frame.field != "" {
+		f = c.Schema.Field(ctx.frame.field)
+	}
+
+	if f == nil { // NO: Can't Suggest Values
+		return items
+	}
+
+	// Known Values
+	if ctx.word.Type == "" || ctx.word.Type == token.STRING || ctx.word.Type == token.IDENT {
+		for _, v := range f.Values {
+			insert := v
+			if f.Type == token.STRING {
+				insert = fmt.Sprintf("\"%s\"", strings.ReplaceAll(v, "\"", "\\\""))
+			}
+
+			if matches(insert, ctx.prefix) || matches(v, ctx.prefix) {
+				items = append(items, &Item{Label: v, Kind: KIND_VALUE, Detail: string(f.Type), Insert: insert})
+			}
+		}
+	}
+
+	// Field References (Comparisons Only)
+	if class == "operator" && (ctx.word.Type == "" || ctx.word.Type == token.FIELD) {
+		for _, rf := range c.Schema.Fields() {
+			if rf.Name == f.Name || rf.Collection || !f.Accepts(rf.Type) {
+				continue
+			}
+
+			label := "@" + rf.Name
+			if matches(label, ctx.prefix) {
+				items = append(items, &Item{Label: label, Kind: KIND_FIELD, Detail: fieldDetail(rf), Insert: label})
+			}
+		}
+	}
+
+	// Expected Type (Hint)
+	if ctx.word.Type == "" && f.Type != schema.POINT {
+		items = append(items, &Item{Label: string(f.Type), Kind: KIND_TYPE, Detail: fmt.Sprintf("%s expects a value of type %s", f.Name, f.Type), Insert: ""})
+	}
+	return items
+}
+
+// Can Field be Used with Function Class?
+func fieldFits(f *schema.Field, class string) bool {
+	switch class {
+	case "operator-collection":
+		return f.Collection
+	case "operator-geo":
+		return f.Type == schema.POINT
+	case "operator-unary":
+		return true
+	case "operator-search":
+		return !f.Collection && f.Type == token.STRING
+	}
+	return !f.Collection && f.Type != schema.POINT
+}
+
+func fieldDetail(f *schema.Field) string {
+	if f.Collection {
+		return fmt.Sprintf("collection of %s", f.Type)
+	}
+	return string(f.Type)
+}
+
+// Case Insensitive Prefix Match
+func matches(label string, prefix string) bool {
+	return strings.HasPrefix(strings.ToLower(label), strings.ToLower(prefix))
+}
+
+// Extend Replacement Range over the Rest of the Word
+func extend(runes []rune, offset int, t token.TokenType) int {
+	end := offset
+	switch t {
+	case token.IDENT, token.FIELD:
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+	case token.STRING:
+		// Include Rest of String (up to the Closing Quote)
+		for i := offset; i < len(runes) && runes[i] != ',' && runes[i] != ')'; i++ {
+			if runes[i] == '"' && runes[i-1] != '\\' {
+				end = i + 1
+				break
+			}
+		}
+	}
+	return end
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '.' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
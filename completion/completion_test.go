@@ -0,0 +1,161 @@
+package completion
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/objectvault/filter-parser/macro"
+	"github.com/objectvault/filter-parser/schema"
+	"github.com/objectvault/filter-parser/syntax"
+	"github.com/objectvault/filter-parser/token"
+)
+
+func testSchema() *schema.Schema {
+	s := schema.NewSchema()
+	s.AddField("state", token.STRING).SetValues("active", "archived")
+	s.AddField("name", token.STRING)
+	s.AddField("created", token.INT)
+	s.AddCollection("tags", token.STRING)
+	s.AddField("loc", schema.POINT)
+	return s
+}
+
+// Complete Input at the Cursor ('|')
+func complete(c *Completer, input string) *Result {
+	offset := len([]rune(input[:strings.Index(input, "|")]))
+	return c.Complete(strings.Replace(input, "|", "", 1), offset)
+}
+
+// Item Labels (Comma Separated)
+func labels(r *Result) string {
+	l := make([]string, 0, len(r.Items))
+	for _, i := range r.Items {
+		l = append(l, i.Label)
+	}
+	return strings.Join(l, ", ")
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		start    int
+		end      int
+	}{
+		{`e|`, "eq, endswith, exists", 0, 1},
+		{`and(eq(state, "a"), o|`, "or", 20, 21},
+		{`and(eq(|`, "created, name, state", 7, 7},
+		{`and(eq(st|`, "state", 7, 9},
+		{`eq(st|ate, "a")`, "state", 3, 8},
+		{`eq(state, "a|`, "active, archived", 10, 12},
+		{`eq(state, |`, "active, archived, @name, STRING", 10, 10},
+		{`eq(state, @|`, "@name", 10, 11},
+		{`eq(created, |`, "INT", 12, 12},
+		{`eq(created, 1|`, "", 12, 13},
+		{`has(|`, "tags", 4, 4},
+		{`near(|`, "loc", 5, 5},
+		{`exists(|`, "created, loc, name, state, tags", 7, 7},
+		{`search(|`, "name, state", 7, 7},
+		{`search(name, "x|`, "", 13, 15},
+		{`eq(zz, |`, "", 7, 7},
+		{`m|`, "matches, missing", 0, 1},
+	}
+
+	c := NewCompleter(testSchema())
+	for i, tt := range tests {
+		r := complete(c, tt.input)
+
+		if got := labels(r); got != tt.expected {
+			t.Fatalf("tests[%d] - items wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+		if r.Start != tt.start || r.End != tt.end {
+			t.Fatalf("tests[%d] - range wrong. expected=[%d, %d), got=[%d, %d)", i, tt.start, tt.end, r.Start, r.End)
+		}
+	}
+}
+
+func TestCompleteInsert(t *testing.T) {
+	c := NewCompleter(testSchema())
+
+	r := complete(c, `eq(state, "ac|`)
+	if len(r.Items) != 1 || r.Items[0].Insert != `"active"` || r.Items[0].Kind != KIND_VALUE {
+		t.Fatalf("value item wrong. got=%+v", r.Items)
+	}
+
+	r = complete(c, `eq|`)
+	if len(r.Items) != 1 || r.Items[0].Insert != "eq(" || r.Items[0].Detail != "eq(field, value)" || r.Items[0].Kind != KIND_FUNCTION {
+		t.Fatalf("function item wrong. got=%+v", r.Items)
+	}
+
+	r = complete(c, `has_any(t|`)
+	if len(r.Items) != 1 || r.Items[0].Detail != "collection of STRING" || r.Items[0].Kind != KIND_FIELD {
+		t.Fatalf("field item wrong. got=%+v", r.Items)
+	}
+}
+
+func TestCompleteMacros(t *testing.T) {
+	r := macro.NewRegistry()
+	r.RegisterText("owned", `eq(owner, $1)`, "owner")
+	r.RegisterText("open", `eq(state, "active")`)
+
+	c := NewCompleter(testSchema())
+	c.Macros = r
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`m|`, "matches, missing, macro"},
+		{`macro(|`, "open, owned"},
+		{`macro(ow|`, "owned"},
+		{`macro(owned, |`, ""},
+	}
+
+	for i, tt := range tests {
+		if got := labels(complete(c, tt.input)); got != tt.expected {
+			t.Fatalf("tests[%d] - items wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestCompleteWithoutSchema(t *testing.T) {
+	c := NewCompleter(nil)
+
+	if got := labels(complete(c, `eq(|`)); got != "" {
+		t.Fatalf("fields without schema. got=%q", got)
+	}
+	if got := labels(complete(c, `not(n|`)); got != "not, neq, near" {
+		t.Fatalf("functions wrong. got=%q", got)
+	}
+
+	// Cursor is Clamped to the Input
+	if r := c.Complete("eq", 10); r.Start != 0 || r.End != 2 || labels(r) != "eq" {
+		t.Fatalf("clamped cursor wrong. got=[%d, %d) %q", r.Start, r.End, labels(r))
+	}
+}
+
+func TestSignatures(t *testing.T) {
+	// Every Known Function has a Signature
+	for _, name := range syntax.FUNCTIONS {
+		if Signature(name) == "" {
+			t.Fatalf("function [%s] has no signature", name)
+		}
+		if Signature(strings.ToLower(name)) != Signature(name) {
+			t.Fatalf("function [%s] signature is case sensitive", name)
+		}
+	}
+
+	if Signature("zz") != "" {
+		t.Fatalf("unknown function has a signature")
+	}
+}
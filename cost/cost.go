@@ -0,0 +1,231 @@
+package cost
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/schema"
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  COST MODEL
+  Every function is scored with:
+  - Cost: fraction of the table that has to be read (1 - full scan)
+  - Selectivity: fraction of the table that matches
+
+  - Indexed, index friendly predicates cost their selectivity
+  - Anything else costs a full scan
+  - AND uses its cheapest branch, OR has to read both branches
+  - NOT can't use the index of the negated predicate
+
+  ASSUMPTION: Filter has been run through the Syntax Checker
+*/
+
+// Flag Codes
+const (
+	FLAG_LEADING_WILDCARD = "LEADING_WILDCARD" // Pattern can't use Index
+	FLAG_NOT_INDEXED      = "NOT_INDEXED"      // NOT over an Indexed Predicate
+	FLAG_UNINDEXED_OR     = "UNINDEXED_OR"     // OR Branch Requires a Full Scan
+	FLAG_FULL_SCAN        = "FULL_SCAN"        // Filter Requires a Full Scan
+)
+
+// Default Selectivity Estimates
+const (
+	RANGE_SELECTIVITY   = 0.3
+	PATTERN_SELECTIVITY = 0.1
+	SEARCH_SELECTIVITY  = 0.05
+)
+
+type Flag struct {
+	Code    string
+	Message string
+}
+
+// Cost Estimate
+type Estimate struct {
+	Cost        float64 // Fraction of Table Read (1 - Full Scan)
+	Selectivity float64 // Fraction of Table Matched
+	Rows        int     // Estimated Rows Read (if Model.Rows is known)
+	Flags       []*Flag
+}
+
+// Is Estimated Cost above the Maximum Allowed?
+func (e *Estimate) Exceeds(max float64) bool {
+	return e.Cost > max
+}
+
+func (e *Estimate) HasFlag(code string) bool {
+	for _, f := range e.Flags {
+		if f.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Cost Model Object
+type Model struct {
+	Schema *schema.Schema // Field Metadata
+	Rows   int            // OPTIONAL: Number of Rows in Table
+}
+
+func NewModel(s *schema.Schema) *Model {
+	m := &Model{Schema: s}
+	return m
+}
+
+func (m *Model) Estimate(f *ast.Filter) *Estimate {
+	e := &Estimate{Cost: 0, Selectivity: 1, Flags: make([]*Flag, 0)}
+
+	// Have Filter?
+	if f == nil || f.F == nil { // NO: Returns all Rows
+		e.Cost = 1
+		e.addFlag(FLAG_FULL_SCAN, "Filter has no conditions")
+	} else {
+		e.Cost, e.Selectivity = m.function(e, f.F)
+		if e.Cost >= 1 {
+			e.addFlag(FLAG_FULL_SCAN, "Filter requires a full table scan")
+		}
+	}
+
+	e.Rows = int(e.Cost * float64(m.Rows))
+	return e
+}
+
+// Cost and Selectivity of Function
+func (m *Model) function(e *Estimate, f *ast.Function) (float64, float64) {
+	fname := strings.ToUpper(f.Name.Literal)
+
+	switch fname {
+	case "AND":
+		c1, s1 := m.function(e, f.Parameters[0].(*ast.Function))
+		c2, s2 := m.function(e, f.Parameters[1].(*ast.Function))
+
+		// Best Branch Drives the Query, Other is Applied to its Rows
+		return min(c1, c2), s1 * s2
+	case "OR":
+		c1, s1 := m.function(e, f.Parameters[0].(*ast.Function))
+		c2, s2 := m.function(e, f.Parameters[1].(*ast.Function))
+
+		// Both Branches have to be Read
+		if c1 >= 1 || c2 >= 1 {
+			e.addFlag(FLAG_UNINDEXED_OR, fmt.Sprintf("[%s] has a branch that requires a full table scan", f.ToString()))
+		}
+		return min(c1+c2, 1), s1 + s2 - s1*s2
+	case "NOT":
+		pf := f.Parameters[0].(*ast.Function)
+		c, s := m.function(e, pf)
+
+		// Negating an Index Friendly Predicate?
+		if c < 1 { // YES: Index is Lost
+			e.addFlag(FLAG_NOT_INDEXED, fmt.Sprintf("NOT over [%s] can't use its index", pf.ToString()))
+		}
+		return 1, 1 - s
+	}
+
+	return m.operator(e, fname, f)
+}
+
+// Cost and Selectivity of Field Operator
+func (m *Model) operator(e *Estimate, fname string, f *ast.Function) (float64, float64) {
+	field := m.field(f)
+
+	// Selectivity of the Operator
+	s := PATTERN_SELECTIVITY
+	if field != nil {
+		s = field.EqualitySelectivity()
+	}
+
+	// Can the Operator use an Index?
+	indexed := true
+	switch fname {
+	case "EQ":
+		// Comparing to another Field can't use the Index
+		indexed = !hasFieldReference(f)
+	case "IN", "HAS", "HAS_ANY", "HAS_ALL":
+		s = min(s*float64(len(f.Parameters)-1), 1)
+	case "GT", "GTE", "LT", "LTE", "BETWEEN":
+		s = RANGE_SELECTIVITY
+		indexed = !hasFieldReference(f)
+	case "STARTSWITH":
+		s = PATTERN_SELECTIVITY
+	case "CONTAINS":
+		s = PATTERN_SELECTIVITY
+
+		// Leading Wildcard?
+		if v, ok := f.Parameters[1].(*ast.Value); ok && strings.HasPrefix(v.V.Literal, "\uFFFD") { // YES: Can't use Index
+			indexed = false
+			e.addFlag(FLAG_LEADING_WILDCARD, fmt.Sprintf("[%s] has a leading wildcard", f.ToString()))
+		}
+	case "SEARCH":
+		s = SEARCH_SELECTIVITY
+	case "NEAR", "WITHIN_BOX":
+		s = PATTERN_SELECTIVITY
+	case "NEQ", "EXISTS":
+		// Matches most Rows
+		s = 1 - s
+		indexed = false
+	case "MISSING":
+		indexed = false
+	default:
+		// ENDSWITH, ICONTAINS, IEQ and MATCHES can't use an Index
+		if fname == "ENDSWITH" {
+			e.addFlag(FLAG_LEADING_WILDCARD, fmt.Sprintf("[%s] has a leading wildcard", f.ToString()))
+		}
+		s = PATTERN_SELECTIVITY
+		indexed = false
+	}
+
+	// Field is Indexed?
+	if indexed && field != nil && field.Indexed { // YES: Only Read Matching Rows
+		return s, s
+	}
+
+	return 1, s
+}
+
+// Schema Field for 1st Field in Operator
+func (m *Model) field(f *ast.Function) *schema.Field {
+	if m.Schema == nil {
+		return nil
+	}
+
+	for _, pi := range f.Parameters {
+		if v, ok := pi.(*ast.Value); ok && v.V.Type == token.IDENT {
+			return m.Schema.Field(v.V.Literal)
+		}
+	}
+	return nil
+}
+
+func hasFieldReference(f *ast.Function) bool {
+	for _, pi := range f.Parameters {
+		if v, ok := pi.(*ast.Value); ok && v.V.Type == token.FIELD {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Estimate) addFlag(code string, message string) {
+	e.Flags = append(e.Flags, &Flag{Code: code, Message: message})
+}
+
+func min(a float64, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,131 @@
+package cost
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"math"
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+	"github.com/objectvault/filter-parser/schema"
+	"github.com/objectvault/filter-parser/token"
+)
+
+// Schema with Indexed (id, state, body) and Unindexed (name) Fields
+func testSchema() *schema.Schema {
+	s := schema.NewSchema()
+	s.AddField("id", token.INT).SetIndex(true, 1000)
+	s.AddField("state", token.STRING).SetIndex(true, 0).Selectivity = 0.5
+	s.AddField("name", token.STRING).SetIndex(false, 100)
+	s.AddField("body", token.STRING).SetIndex(true, 0)
+	return s
+}
+
+// Parse Input (Fails Test if Input is not a Valid Filter)
+func parseFilter(t *testing.T, input string) *ast.Filter {
+	r := parser.NewParser(lexer.NewLexer(input)).ParseFilter()
+
+	f, ok := r.(*ast.Filter)
+	if !ok {
+		t.Fatalf("parse [%s] failed. got=%T (%+v)", input, r, r)
+	}
+	return f
+}
+
+func equal(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		input       string
+		cost        float64
+		selectivity float64
+		flags       []string
+	}{
+		{`eq(id, 1)`, 0.001, 0.001, nil},
+		{`eq(name, "x")`, 1, 0.01, []string{FLAG_FULL_SCAN}},
+		{`eq(id, @id)`, 1, 0.001, []string{FLAG_FULL_SCAN}},
+		{`in(id, 1, 2, 3)`, 0.003, 0.003, nil},
+		{`gt(id, 5)`, RANGE_SELECTIVITY, RANGE_SELECTIVITY, nil},
+		{`neq(id, 1)`, 1, 0.999, []string{FLAG_FULL_SCAN}},
+		{`startswith(state, "a")`, PATTERN_SELECTIVITY, PATTERN_SELECTIVITY, nil},
+		{`contains(state, "a*")`, PATTERN_SELECTIVITY, PATTERN_SELECTIVITY, nil},
+		{`contains(state, "*a")`, 1, PATTERN_SELECTIVITY, []string{FLAG_LEADING_WILDCARD, FLAG_FULL_SCAN}},
+		{`endswith(state, "a")`, 1, PATTERN_SELECTIVITY, []string{FLAG_LEADING_WILDCARD, FLAG_FULL_SCAN}},
+		{`search(body, "x")`, SEARCH_SELECTIVITY, SEARCH_SELECTIVITY, nil},
+		{`and(eq(id, 1), eq(name, "x"))`, 0.001, 0.00001, nil},
+		{`or(eq(id, 1), eq(state, "a"))`, 0.501, 0.5005, nil},
+		{`or(eq(id, 1), eq(name, "x"))`, 1, 0.01099, []string{FLAG_UNINDEXED_OR, FLAG_FULL_SCAN}},
+		{`not(eq(id, 1))`, 1, 0.999, []string{FLAG_NOT_INDEXED, FLAG_FULL_SCAN}},
+		{`not(eq(name, "x"))`, 1, 0.99, []string{FLAG_FULL_SCAN}},
+	}
+
+	m := NewModel(testSchema())
+	for i, tt := range tests {
+		e := m.Estimate(parseFilter(t, tt.input))
+
+		if !equal(e.Cost, tt.cost) {
+			t.Fatalf("tests[%d] - cost wrong. expected=%v, got=%v", i, tt.cost, e.Cost)
+		}
+		if !equal(e.Selectivity, tt.selectivity) {
+			t.Fatalf("tests[%d] - selectivity wrong. expected=%v, got=%v", i, tt.selectivity, e.Selectivity)
+		}
+		if len(e.Flags) != len(tt.flags) {
+			t.Fatalf("tests[%d] - flags wrong. expected=%v, got=%d", i, tt.flags, len(e.Flags))
+		}
+		for j, code := range tt.flags {
+			if e.Flags[j].Code != code {
+				t.Fatalf("tests[%d][%d] - flag wrong. expected=%q, got=%q", i, j, code, e.Flags[j].Code)
+			}
+		}
+	}
+}
+
+func TestEstimateWithoutSchema(t *testing.T) {
+	e := NewModel(nil).Estimate(parseFilter(t, `eq(id, 1)`))
+	if e.Cost != 1 || !equal(e.Selectivity, PATTERN_SELECTIVITY) || !e.HasFlag(FLAG_FULL_SCAN) {
+		t.Fatalf("estimate wrong. got=%+v", e)
+	}
+
+	e = NewModel(nil).Estimate(nil)
+	if e.Cost != 1 || e.Selectivity != 1 || !e.HasFlag(FLAG_FULL_SCAN) {
+		t.Fatalf("empty filter estimate wrong. got=%+v", e)
+	}
+}
+
+func TestRows(t *testing.T) {
+	m := NewModel(testSchema())
+	m.Rows = 10000
+
+	tests := []struct {
+		input   string
+		rows    int
+		exceeds bool
+		maxCost float64
+	}{
+		{`eq(id, 1)`, 10, false, 0.1},
+		{`gt(id, 1)`, 3000, true, 0.1},
+		{`eq(name, "x")`, 10000, true, 0.5},
+	}
+
+	for i, tt := range tests {
+		e := m.Estimate(parseFilter(t, tt.input))
+		if e.Rows != tt.rows {
+			t.Fatalf("tests[%d] - rows wrong. expected=%d, got=%d", i, tt.rows, e.Rows)
+		}
+		if e.Exceeds(tt.maxCost) != tt.exceeds {
+			t.Fatalf("tests[%d] - exceeds wrong. expected=%t, got=%t", i, tt.exceeds, e.Exceeds(tt.maxCost))
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package describe
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strings"
+	"sync"
+)
+
+/*
+  MESSAGE CATALOG
+  Operator phrases are fmt formats, with the arguments:
+  - Field Operators: field label, then each value (%[1]s, %[2]s, ...)
+  - List Operators (IN, HAS_ANY, HAS_ALL): field label, joined values
+  - SEARCH: joined field labels, search terms
+  - NEAR: field label, latitude, longitude, radius (meters)
+*/
+
+// Message Catalog Object
+type Catalog struct {
+	Language  string
+	And       string            // Logical AND Format (lhs, rhs)
+	Or        string            // Logical OR Format (lhs, rhs)
+	Not       string            // Logical NOT Format (negated description)
+	Separator string            // List Separator
+	Operators map[string]string // Operator Phrases
+	Negated   map[string]string // OPTIONAL: Phrases for NOT(Operator)
+}
+
+var English = &Catalog{
+	Language:  "en",
+	And:       "%s and %s",
+	Or:        "%s or %s",
+	Not:       "not (%s)",
+	Separator: ", ",
+	Operators: map[string]string{
+		"EQ":         "%s is %s",
+		"NEQ":        "%s is not %s",
+		"GT":         "%s is greater than %s",
+		"GTE":        "%s is greater than or equal to %s",
+		"LT":         "%s is less than %s",
+		"LTE":        "%s is less than or equal to %s",
+		"CONTAINS":   "%s matches %s",
+		"STARTSWITH": "%s starts with %s",
+		"ENDSWITH":   "%s ends with %s",
+		"ICONTAINS":  "%s matches %s (ignoring case)",
+		"IEQ":        "%s is %s (ignoring case)",
+		"MATCHES":    "%s matches the regular expression %s",
+		"IN":         "%s is one of %s",
+		"BETWEEN":    "%s is between %s and %s",
+		"EXISTS":     "%s is present",
+		"MISSING":    "%s is missing",
+		"HAS":        "%s includes %s",
+		"HAS_ANY":    "%s includes any of %s",
+		"HAS_ALL":    "%s includes all of %s",
+		"SEARCH":     "%s contain the words %s",
+		"NEAR":       "%[1]s is within %[4]s meters of %[2]s, %[3]s",
+		"WITHIN_BOX": "%[1]s is inside the box %[2]s, %[3]s to %[4]s, %[5]s",
+	},
+	Negated: map[string]string{
+		"EQ":         "%s is not %s",
+		"NEQ":        "%s is %s",
+		"CONTAINS":   "%s does not match %s",
+		"STARTSWITH": "%s does not start with %s",
+		"ENDSWITH":   "%s does not end with %s",
+		"ICONTAINS":  "%s does not match %s (ignoring case)",
+		"IEQ":        "%s is not %s (ignoring case)",
+		"MATCHES":    "%s does not match the regular expression %s",
+		"IN":         "%s is not one of %s",
+		"BETWEEN":    "%s is not between %s and %s",
+		"EXISTS":     "%s is missing",
+		"MISSING":    "%s is present",
+		"HAS":        "%s does not include %s",
+		"HAS_ANY":    "%s includes none of %s",
+	},
+}
+
+var Portuguese = &Catalog{
+	Language:  "pt",
+	And:       "%s e %s",
+	Or:        "%s ou %s",
+	Not:       "não (%s)",
+	Separator: ", ",
+	Operators: map[string]string{
+		"EQ":         "%s é %s",
+		"NEQ":        "%s não é %s",
+		"GT":         "%s é maior que %s",
+		"GTE":        "%s é maior ou igual a %s",
+		"LT":         "%s é menor que %s",
+		"LTE":        "%s é menor ou igual a %s",
+		"CONTAINS":   "%s corresponde a %s",
+		"STARTSWITH": "%s começa com %s",
+		"ENDSWITH":   "%s termina com %s",
+		"ICONTAINS":  "%s corresponde a %s (ignorando maiúsculas)",
+		"IEQ":        "%s é %s (ignorando maiúsculas)",
+		"MATCHES":    "%s corresponde à expressão regular %s",
+		"IN":         "%s é um de %s",
+		"BETWEEN":    "%s está entre %s e %s",
+		"EXISTS":     "%s está presente",
+		"MISSING":    "%s está ausente",
+		"HAS":        "%s inclui %s",
+		"HAS_ANY":    "%s inclui algum de %s",
+		"HAS_ALL":    "%s inclui todos de %s",
+		"SEARCH":     "%s contêm as palavras %s",
+		"NEAR":       "%[1]s está a menos de %[4]s metros de %[2]s, %[3]s",
+		"WITHIN_BOX": "%[1]s está dentro da área %[2]s, %[3]s a %[4]s, %[5]s",
+	},
+	Negated: map[string]string{
+		"EQ":         "%s não é %s",
+		"NEQ":        "%s é %s",
+		"CONTAINS":   "%s não corresponde a %s",
+		"STARTSWITH": "%s não começa com %s",
+		"ENDSWITH":   "%s não termina com %s",
+		"ICONTAINS":  "%s não corresponde a %s (ignorando maiúsculas)",
+		"IEQ":        "%s não é %s (ignorando maiúsculas)",
+		"MATCHES":    "%s não corresponde à expressão regular %s",
+		"IN":         "%s não é um de %s",
+		"BETWEEN":    "%s não está entre %s e %s",
+		"EXISTS":     "%s está ausente",
+		"MISSING":    "%s está presente",
+		"HAS":        "%s não inclui %s",
+		"HAS_ANY":    "%s não inclui nenhum de %s",
+	},
+}
+
+// Registered Catalogs (by Language)
+var catalogs = map[string]*Catalog{
+	"en": English,
+	"pt": Portuguese,
+}
+
+// Guards Registered Catalogs (Register and Lookup can be Concurrent)
+var catalogsLock sync.RWMutex
+
+func RegisterCatalog(c *Catalog) {
+	catalogsLock.Lock()
+	defer catalogsLock.Unlock()
+
+	catalogs[strings.ToLower(c.Language)] = c
+}
+
+// Catalog for Language (i.e. "pt-BR" falls back to "pt", then English)
+func Lookup(language string) *Catalog {
+	catalogsLock.RLock()
+	defer catalogsLock.RUnlock()
+
+	language = strings.ToLower(language)
+	if c, ok := catalogs[language]; ok {
+		return c
+	}
+
+	// Try Base Language
+	if i := strings.IndexAny(language, "-_"); i > 0 {
+		if c, ok := catalogs[language[:i]]; ok {
+			return c
+		}
+	}
+
+	return English
+}
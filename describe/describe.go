@@ -0,0 +1,168 @@
+package describe
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  DESCRIPTIONS
+  and(gt(type, 1), not(contains(alias, "*org*")))
+  => "Type is greater than 1 and alias does not match *org*"
+
+  - Nested logical operators, of a different type, are parenthesized
+  - String values are shown without quotes, wildcards as '*'
+  - Field references (@field) use the field label
+
+  ASSUMPTION: Filter has been run through the Syntax Checker
+*/
+
+// Field Label Callback (i.e. "created" => "Creation Date")
+type TFieldLabel = func(field string) string
+
+// Describer Object
+type Describer struct {
+	Catalog *Catalog          // Message Catalog
+	Label   TFieldLabel       // OPTIONAL: Field Labels (nil - Field Name)
+	Phrases map[string]string // OPTIONAL: Operator Phrases (override Catalog, "NOT EQ" overrides Negated)
+}
+
+func NewDescriber(c *Catalog) *Describer {
+	// Have Catalog?
+	if c == nil { // NO: Use Default
+		c = English
+	}
+
+	d := &Describer{Catalog: c}
+	return d
+}
+
+func (d *Describer) Describe(f *ast.Filter) string {
+	// Have Conditions?
+	if f == nil || f.F == nil { // NO
+		return ""
+	}
+
+	return capitalize(d.function(f.F, ""))
+}
+
+// Describe Function (parent - name of parent logical operator)
+func (d *Describer) function(f *ast.Function, parent string) string {
+	fname := strings.ToUpper(f.Name.Literal)
+
+	switch fname {
+	case "AND", "OR":
+		format := d.Catalog.And
+		if fname == "OR" {
+			format = d.Catalog.Or
+		}
+
+		s := fmt.Sprintf(format, d.function(f.Parameters[0].(*ast.Function), fname), d.function(f.Parameters[1].(*ast.Function), fname))
+
+		// Different Operator than Parent?
+		if parent != "" && parent != fname { // YES: Group it
+			s = "(" + s + ")"
+		}
+		return s
+	case "NOT":
+		pf := f.Parameters[0].(*ast.Function)
+		pname := strings.ToUpper(pf.Name.Literal)
+
+		// Have Negated Phrase Override?
+		if format, ok := d.Phrases["NOT "+pname]; ok { // YES
+			return d.operator(pf, format)
+		}
+
+		// Does the Catalog have a Negated Phrase (and the Operator Phrase isn't Overridden)?
+		if _, overridden := d.Phrases[pname]; !overridden {
+			if format, ok := d.Catalog.Negated[pname]; ok { // YES
+				return d.operator(pf, format)
+			}
+		}
+
+		return fmt.Sprintf(d.Catalog.Not, d.function(pf, ""))
+	}
+
+	// Have Phrase for Operator?
+	format, ok := d.Phrases[fname]
+	if !ok {
+		format, ok = d.Catalog.Operators[fname]
+	}
+
+	if !ok { // NO: Use Filter Syntax
+		return f.ToString()
+	}
+
+	return d.operator(f, format)
+}
+
+func (d *Describer) operator(f *ast.Function, format string) string {
+	args := make([]interface{}, 0, len(f.Parameters))
+
+	switch strings.ToUpper(f.Name.Literal) {
+	case "IN", "HAS_ANY", "HAS_ALL":
+		args = append(args, d.value(f.Parameters[0].(*ast.Value)), d.list(f.Parameters[1:]))
+	case "SEARCH":
+		last := len(f.Parameters) - 1
+		args = append(args, d.list(f.Parameters[:last]), d.value(f.Parameters[last].(*ast.Value)))
+	default:
+		for _, pi := range f.Parameters {
+			args = append(args, d.value(pi.(*ast.Value)))
+		}
+	}
+
+	return fmt.Sprintf(format, args...)
+}
+
+func (d *Describer) list(values []interface{}) string {
+	l := make([]string, 0, len(values))
+	for _, pi := range values {
+		l = append(l, d.value(pi.(*ast.Value)))
+	}
+	return strings.Join(l, d.Catalog.Separator)
+}
+
+func (d *Describer) value(v *ast.Value) string {
+	switch v.V.Type {
+	case token.IDENT, token.FIELD:
+		return d.label(v.V.Literal)
+	case token.STRING:
+		return strings.ReplaceAll(v.V.Literal, "\uFFFD", "*")
+	case token.PARAM:
+		return "$" + v.V.Literal
+	}
+	return v.V.Literal
+}
+
+func (d *Describer) label(field string) string {
+	if d.Label != nil {
+		if l := d.Label(field); l != "" {
+			return l
+		}
+	}
+	return field
+}
+
+// Upper Case 1st Letter
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
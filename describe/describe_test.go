@@ -0,0 +1,180 @@
+package describe
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+)
+
+// Parse Input (Fails Test if Input is not a Valid Filter)
+func parseFilter(t *testing.T, input string) *ast.Filter {
+	r := parser.NewParser(lexer.NewLexer(input)).ParseFilter()
+
+	f, ok := r.(*ast.Filter)
+	if !ok {
+		t.Fatalf("parse [%s] failed. got=%T (%+v)", input, r, r)
+	}
+	return f
+}
+
+func TestEnglish(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`eq(state, "active")`, "State is active"},
+		{`and(gt(type, 1), not(contains(alias, "*org*")))`, "Type is greater than 1 and alias does not match *org*"},
+		{`or(eq(a, 1), and(eq(b, 2), eq(c, 3)))`, "A is 1 or (b is 2 and c is 3)"},
+		{`and(eq(a, 1), and(eq(b, 2), eq(c, 3)))`, "A is 1 and b is 2 and c is 3"},
+		{`in(id, 1, 2, 3)`, "Id is one of 1, 2, 3"},
+		{`not(has_all(tags, "x", "y"))`, "Not (tags includes all of x, y)"},
+		{`eq(a, @b)`, "A is b"},
+		{`eq(a, $1)`, "A is $1"},
+		{`between(n, 1, 10)`, "N is between 1 and 10"},
+		{`search(title, body, "go")`, "Title, body contain the words go"},
+		{`near(loc, 40.7, -74.0, 500)`, "Loc is within 500 meters of 40.7, -74.0"},
+		{`not(missing(a))`, "A is present"},
+	}
+
+	d := NewDescriber(nil)
+	for i, tt := range tests {
+		got := d.Describe(parseFilter(t, tt.input))
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - description wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestPortuguese(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`eq(state, "active")`, "State é active"},
+		{`and(gt(type, 1), not(contains(alias, "*org*")))`, "Type é maior que 1 e alias não corresponde a *org*"},
+		{`or(eq(a, 1), and(eq(b, 2), eq(c, 3)))`, "A é 1 ou (b é 2 e c é 3)"},
+		{`in(id, 1, 2)`, "Id é um de 1, 2"},
+		{`not(has_all(tags, "x"))`, "Não (tags inclui todos de x)"},
+		{`near(loc, 40.7, -74.0, 500)`, "Loc está a menos de 500 metros de 40.7, -74.0"},
+	}
+
+	d := NewDescriber(Lookup("pt-BR"))
+	for i, tt := range tests {
+		got := d.Describe(parseFilter(t, tt.input))
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - description wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestCatalogs(t *testing.T) {
+	// Every Catalog Describes Every Operator the English Catalog Does
+	for _, c := range []*Catalog{English, Portuguese} {
+		for op := range English.Operators {
+			if _, ok := c.Operators[op]; !ok {
+				t.Fatalf("catalog [%s] - missing operator [%s]", c.Language, op)
+			}
+		}
+		for op := range English.Negated {
+			if _, ok := c.Negated[op]; !ok {
+				t.Fatalf("catalog [%s] - missing negated operator [%s]", c.Language, op)
+			}
+		}
+	}
+
+	tests := []struct {
+		language string
+		expected *Catalog
+	}{
+		{"en", English},
+		{"PT", Portuguese},
+		{"pt_PT", Portuguese},
+		{"fr", English},
+		{"", English},
+	}
+
+	for i, tt := range tests {
+		if got := Lookup(tt.language); got != tt.expected {
+			t.Fatalf("tests[%d] - catalog wrong. expected=%q, got=%q", i, tt.expected.Language, got.Language)
+		}
+	}
+}
+
+func TestRegisterCatalog(t *testing.T) {
+	c := &Catalog{Language: "XX", And: "%s & %s", Or: "%s | %s", Not: "!(%s)", Separator: "; ", Operators: map[string]string{"EQ": "%s = %s"}}
+
+	// Concurrent Registration and Lookup
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterCatalog(c)
+		}()
+		go func() {
+			defer wg.Done()
+			Lookup("xx")
+		}()
+	}
+	wg.Wait()
+
+	d := NewDescriber(Lookup("xx-YY"))
+	got := d.Describe(parseFilter(t, `not(and(eq(a, 1), gt(b, 2)))`))
+	if expected := "!(a = 1 & gt ( b, 2 ))"; got != expected {
+		t.Fatalf("description wrong. expected=%q, got=%q", expected, got)
+	}
+}
+
+func TestPhrases(t *testing.T) {
+	tests := []struct {
+		input    string
+		phrases  map[string]string
+		expected string
+	}{
+		{`eq(a, 1)`, map[string]string{"EQ": "%s equals %s"}, "A equals 1"},
+		// Overridden Operator: Catalog Negated Phrase no Longer Applies
+		{`not(eq(a, 1))`, map[string]string{"EQ": "%s equals %s"}, "Not (a equals 1)"},
+		{`not(eq(a, 1))`, map[string]string{"EQ": "%s equals %s", "NOT EQ": "%s differs from %s"}, "A differs from 1"},
+		{`not(eq(a, 1))`, map[string]string{"NOT EQ": "%s differs from %s"}, "A differs from 1"},
+		{`not(gt(a, 1))`, map[string]string{"NOT GT": "%s is at most %s"}, "A is at most 1"},
+		{`not(neq(a, 1))`, map[string]string{"EQ": "%s equals %s"}, "A is 1"},
+	}
+
+	for i, tt := range tests {
+		d := NewDescriber(nil)
+		d.Phrases = tt.phrases
+
+		got := d.Describe(parseFilter(t, tt.input))
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - description wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	d := NewDescriber(nil)
+	d.Label = func(field string) string {
+		if field == "created" {
+			return "creation date"
+		}
+		return ""
+	}
+
+	got := d.Describe(parseFilter(t, `and(gt(created, @updated), eq(state, "a*"))`))
+	if expected := "Creation date is greater than updated and state is a*"; got != expected {
+		t.Fatalf("description wrong. expected=%q, got=%q", expected, got)
+	}
+}
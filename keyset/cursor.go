@@ -0,0 +1,115 @@
+package keyset
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/builder"
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  CURSOR FORMAT
+  base64url(payload) "." base64url(HMAC-SHA256(secret, sort + payload))
+
+  - payload is a JSON list of [type, literal] pairs
+  - the sort is part of the signature, so a cursor is only valid for the
+    sort it was created with
+*/
+
+// Convert Last Row to Keyset Values
+func FromRow(s *ast.Sort, row map[string]interface{}) ([]*ast.Value, *KeysetError) {
+	values := make([]*ast.Value, 0, len(s.Fields))
+	for _, sf := range s.Fields {
+		rv, ok := row[sf.Field.Literal]
+		if !ok {
+			return nil, &KeysetError{Message: fmt.Sprintf("Row is missing Field [%s]", sf.Field.Literal)}
+		}
+
+		v := builder.ASTLiteral(rv)
+		if v == nil {
+			return nil, &KeysetError{Message: fmt.Sprintf("Row Field [%s] has unsupported type [%T]", sf.Field.Literal, rv)}
+		}
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+func EncodeCursor(secret []byte, s *ast.Sort, values []*ast.Value) string {
+	pairs := make([][2]string, 0, len(values))
+	for _, v := range values {
+		pairs = append(pairs, [2]string{string(v.V.Type), v.V.Literal})
+	}
+
+	// NOTE: Marshalling a List of Strings can't Fail
+	payload, _ := json.Marshal(pairs)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(sign(secret, s, payload))
+}
+
+func DecodeCursor(secret []byte, s *ast.Sort, cursor string) ([]*ast.Value, *KeysetError) {
+	parts := strings.Split(cursor, ".")
+	if len(parts) != 2 {
+		return nil, &KeysetError{Message: "Invalid Cursor"}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, &KeysetError{Message: "Invalid Cursor"}
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, &KeysetError{Message: "Invalid Cursor"}
+	}
+
+	// Has Cursor been Tampered with?
+	if !hmac.Equal(signature, sign(secret, s, payload)) { // YES
+		return nil, &KeysetError{Message: "Invalid Cursor Signature"}
+	}
+
+	var pairs [][2]string
+	if err := json.Unmarshal(payload, &pairs); err != nil {
+		return nil, &KeysetError{Message: "Invalid Cursor"}
+	}
+
+	if len(pairs) != len(s.Fields) {
+		return nil, &KeysetError{Message: fmt.Sprintf("Cursor expects [%d] values, found [%d]", len(s.Fields), len(pairs))}
+	}
+
+	values := make([]*ast.Value, 0, len(pairs))
+	for _, pair := range pairs {
+		t := token.TokenType(pair[0])
+		if t != token.STRING && t != token.INT && t != token.NUMBER {
+			return nil, &KeysetError{Message: fmt.Sprintf("Cursor has invalid value type [%s]", t)}
+		}
+		values = append(values, builder.ASTValue(t, pair[1]))
+	}
+
+	return values, nil
+}
+
+func sign(secret []byte, s *ast.Sort, payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(s.ToString()))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,93 @@
+package keyset
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/builder"
+)
+
+/*
+  KEYSET PAGINATION
+  For sort(k1, k2 desc, k3) and last row values (v1, v2, v3) the rows
+  that follow are:
+
+    or(gt(k1, v1),
+       or(and(eq(k1, v1), lt(k2, v2)),
+          and(eq(k1, v1), and(eq(k2, v2), gt(k3, v3)))))
+
+  NOTE: The sort should end in a unique field (i.e. id) or rows that share
+  the same key values will be skipped.
+  NOTE: Sort keys should be NOT NULL. NULL never compares equal, so FromRow
+  rejects rows with a NULL key.
+*/
+
+// Keyset Error Object
+type KeysetError struct {
+	Message string
+}
+
+func (e *KeysetError) ToString() string {
+	return e.Message
+}
+
+// Filter for Rows after the Last Row (Filter can be nil)
+func After(f *ast.Filter, s *ast.Sort, values []*ast.Value) (*ast.Filter, *KeysetError) {
+	p, e := Predicate(s, values)
+	if e != nil {
+		return nil, e
+	}
+
+	// Have Filter?
+	if f == nil || f.F == nil { // NO: Keyset Predicate is the Filter
+		return builder.ASTFilter(p), nil
+	}
+
+	return builder.ASTFilter(builder.ASTAND(f.F, p)), nil
+}
+
+// Keyset Predicate for Rows after the Last Row
+func Predicate(s *ast.Sort, values []*ast.Value) (*ast.Function, *KeysetError) {
+	if s == nil || len(s.Fields) == 0 {
+		return nil, &KeysetError{Message: "Keyset requires a sort"}
+	}
+
+	if len(values) != len(s.Fields) {
+		return nil, &KeysetError{Message: fmt.Sprintf("Keyset expects [%d] values, found [%d]", len(s.Fields), len(values))}
+	}
+
+	// Build from the Last Sort Field Back (OR is Binary)
+	var p *ast.Function
+	for i := len(s.Fields) - 1; i >= 0; i-- {
+		// Rows with the Same Keys up to i, and Past the Key at i
+		t := after(s.Fields[i], values[i])
+		for j := i - 1; j >= 0; j-- {
+			t = builder.ASTAND(builder.ASTEQ(s.Fields[j].Field.Literal, values[j]), t)
+		}
+
+		if p == nil {
+			p = t
+		} else {
+			p = builder.ASTOR(t, p)
+		}
+	}
+
+	return p, nil
+}
+
+func after(sf *ast.SortField, v *ast.Value) *ast.Function {
+	if sf.Descending {
+		return builder.ASTLT(sf.Field.Literal, v)
+	}
+	return builder.ASTGT(sf.Field.Literal, v)
+}
@@ -0,0 +1,165 @@
+package keyset
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+	"github.com/objectvault/filter-parser/transpiler"
+)
+
+// Sort Clause from Query Text (i.e. "sort(a, b desc)")
+func parseSort(t *testing.T, input string) *ast.Sort {
+	r := parser.NewParser(lexer.NewLexer(input)).ParseQuery()
+
+	q, ok := r.(*ast.Query)
+	if !ok || q.Sort == nil {
+		t.Fatalf("parse [%s] failed. got=%T (%+v)", input, r, r)
+	}
+	return q.Sort
+}
+
+// Last Row as Keyset Values
+func rowValues(t *testing.T, s *ast.Sort, row map[string]interface{}) []*ast.Value {
+	values, e := FromRow(s, row)
+	if e != nil {
+		t.Fatalf("FromRow failed. got=%q", e.Message)
+	}
+	return values
+}
+
+func TestAfter(t *testing.T) {
+	tests := []struct {
+		sort     string
+		row      map[string]interface{}
+		expected string
+	}{
+		{`sort(id)`, map[string]interface{}{"id": 10}, "id > 10"},
+		{`sort(id desc)`, map[string]interface{}{"id": 10}, "id < 10"},
+		{`sort(name, id)`, map[string]interface{}{"name": "bob", "id": 7},
+			`(name > "bob") OR ((name = "bob") AND (id > 7))`},
+		{`sort(name desc, id)`, map[string]interface{}{"name": "bob", "id": 7},
+			`(name < "bob") OR ((name = "bob") AND (id > 7))`},
+		{`sort(score desc, name, id desc)`, map[string]interface{}{"score": 1.5, "name": "a", "id": 3},
+			`(score < 1.5) OR (((score = 1.5) AND (name > "a")) OR ((score = 1.5) AND ((name = "a") AND (id < 3))))`},
+		{`sort(name, id)`, map[string]interface{}{"name": "50%_off", "id": 1},
+			`(name > "50%_off") OR ((name = "50%_off") AND (id > 1))`},
+		{`sort(name, id)`, map[string]interface{}{"name": `a*"b'`, "id": 1},
+			`(name > "a*\"b\'") OR ((name = "a*\"b\'") AND (id > 1))`},
+	}
+
+	for i, tt := range tests {
+		s := parseSort(t, tt.sort)
+
+		f, e := After(nil, s, rowValues(t, s, tt.row))
+		if e != nil {
+			t.Fatalf("tests[%d] - After failed. got=%q", i, e.Message)
+		}
+
+		got := transpiler.NewTranspileToMysqlWhere(f, nil).Transpile()
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestAfterFilter(t *testing.T) {
+	s := parseSort(t, `sort(id)`)
+	f := parser.NewParser(lexer.NewLexer(`EQ(owner, 1)`)).ParseFilter().(*ast.Filter)
+
+	a, e := After(f, s, rowValues(t, s, map[string]interface{}{"id": 5}))
+	if e != nil {
+		t.Fatalf("After failed. got=%q", e.Message)
+	}
+
+	expected := "(owner = 1) AND (id > 5)"
+	if got := transpiler.NewTranspileToMysqlWhere(a, nil).Transpile(); got != expected {
+		t.Fatalf("sql wrong. expected=%q, got=%q", expected, got)
+	}
+}
+
+func TestKeysetErrors(t *testing.T) {
+	s := parseSort(t, `sort(name, id)`)
+
+	tests := []struct {
+		row           map[string]interface{}
+		expectedError string
+	}{
+		{map[string]interface{}{"name": "a"}, "Row is missing Field [id]"},
+		{map[string]interface{}{"name": nil, "id": 1}, "Row Field [name] has unsupported type [<nil>]"},
+		{map[string]interface{}{"name": true, "id": 1}, "Row Field [name] has unsupported type [bool]"},
+	}
+
+	for i, tt := range tests {
+		_, e := FromRow(s, tt.row)
+		if e == nil || e.Message != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%+v", i, tt.expectedError, e)
+		}
+	}
+
+	if _, e := Predicate(nil, nil); e == nil || e.Message != "Keyset requires a sort" {
+		t.Fatalf("nil sort - error wrong. got=%+v", e)
+	}
+
+	if _, e := Predicate(s, rowValues(t, parseSort(t, `sort(id)`), map[string]interface{}{"id": 1})); e == nil || e.Message != "Keyset expects [2] values, found [1]" {
+		t.Fatalf("short values - error wrong. got=%+v", e)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	secret := []byte("secret")
+	s := parseSort(t, `sort(name, id desc)`)
+	values := rowValues(t, s, map[string]interface{}{"name": "bob", "id": 7})
+
+	cursor := EncodeCursor(secret, s, values)
+
+	// Round Trip
+	decoded, e := DecodeCursor(secret, s, cursor)
+	if e != nil {
+		t.Fatalf("DecodeCursor failed. got=%q", e.Message)
+	}
+
+	for i, v := range decoded {
+		if v.V != values[i].V {
+			t.Fatalf("values[%d] - value wrong. expected=%+v, got=%+v", i, values[i].V, v.V)
+		}
+	}
+
+	// Tampered Payload (Same Signature)
+	other := EncodeCursor(secret, s, rowValues(t, s, map[string]interface{}{"name": "eve", "id": 1}))
+	tampered := other[:strings.Index(other, ".")] + cursor[strings.Index(cursor, "."):]
+
+	tests := []struct {
+		secret        []byte
+		sort          *ast.Sort
+		cursor        string
+		expectedError string
+	}{
+		{secret, s, tampered, "Invalid Cursor Signature"},
+		{[]byte("other"), s, cursor, "Invalid Cursor Signature"},
+		{secret, parseSort(t, `sort(name, id)`), cursor, "Invalid Cursor Signature"},
+		{secret, s, cursor + "x", "Invalid Cursor Signature"},
+		{secret, s, "abc", "Invalid Cursor"},
+		{secret, s, "a.b.c", "Invalid Cursor"},
+		{secret, s, "!!." + cursor[strings.Index(cursor, ".")+1:], "Invalid Cursor"},
+	}
+
+	for i, tt := range tests {
+		_, e := DecodeCursor(tt.secret, tt.sort, tt.cursor)
+		if e == nil || e.Message != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%+v", i, tt.expectedError, e)
+		}
+	}
+}
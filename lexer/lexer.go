@@ -22,6 +22,9 @@ type Lexer struct {
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
 	ch           rune // current char under examination
+	start        int  // start of last token (in characters)
+	end          int  // end of last token + 1 (in characters)
+	MaxLength    int  // OPTIONAL: Maximum Input Length (in characters, 0 - No Limit)
 }
 
 func NewLexer(input string) *Lexer {
@@ -37,20 +40,43 @@ func (l *Lexer) Reset() *Lexer {
 	// Reset Positions
 	l.position = 0
 	l.readPosition = 0
+	l.start, l.end = 0, 0
 
 	// Load 1st Unicode Character
 	l.nextChar()
 	return l
 }
 
+// Input Length (in Characters)
+func (l *Lexer) Length() int {
+	return len(l.input)
+}
+
+// Position of Last Token in Input [start, end) (in Characters)
+func (l *Lexer) Span() (int, int) {
+	return l.start, l.end
+}
+
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
+	// Input too Long (and not yet Rejected)?
+	if l.MaxLength > 0 && len(l.input) > l.MaxLength && l.ch != 0 { // YES: Whole Input is a single ILLEGAL Token
+		l.start, l.end = 0, len(l.input)
+
+		// Skip to EOL
+		l.readPosition = len(l.input)
+		l.nextChar()
+		return token.Token{Type: token.ILLEGAL, Literal: ""}
+	}
+
 	// Skip Leading Whitespaces
 	l.skipWhiteSpaces()
 
+	// MARK Start of Token
+	l.start = l.position
+
 	// See what we have as the current character
-	// start := l.position
 	if l.ch == '(' {
 		tok = newToken(token.LPAREN, l.ch)
 	} else if l.ch == ')' {
@@ -60,12 +86,16 @@ func (l *Lexer) NextToken() token.Token {
 	} else if l.ch == 0 { // EOL: Marker
 		// NOTE: l.ch contain run '\x00'
 		tok = newToken(token.EOL, l.ch)
-	} else if isValidNumberRune(l.ch) {
+	} else if isValidNumberRune(l.ch) || (l.ch == '-' && isValidNumberRune(l.peekChar(l.readPosition))) {
 		tok = l.nextTokenNumber()
 	} else if isValidFirstIdentifierRune(l.ch) {
 		tok = l.nextTokenIdentifier()
 	} else if l.ch == '"' {
 		tok = l.nextTokenString()
+	} else if l.ch == '@' {
+		tok = l.nextTokenField()
+	} else if l.ch == '$' {
+		tok = l.nextTokenParam()
 	} else {
 		tok = newToken(token.ILLEGAL, l.ch)
 	}
@@ -73,6 +103,9 @@ func (l *Lexer) NextToken() token.Token {
 
 	// Move Forward in Stream
 	l.nextChar()
+
+	// MARK End of Token + 1
+	l.end = l.position
 	return tok
 }
 
@@ -81,6 +114,14 @@ func (l *Lexer) nextTokenNumber() token.Token {
 	requireNextDigit := false // Next Character has to be a Digit?
 	tt := token.INT           // Set Default Number Type
 
+	// MARK Start of Number
+	start := l.position
+
+	// Negative Number?
+	if l.ch == '-' { // YES: Sign is part of the Number
+		l.nextChar()
+	}
+
 	// Did we start with a decimal?
 	if l.ch == '.' {
 		tt = token.NUMBER
@@ -88,8 +129,6 @@ func (l *Lexer) nextTokenNumber() token.Token {
 		matchedPeriod = true
 	}
 
-	// MARK Start of Number
-	start := l.position
 	for l.nextChar(); isValidNumberRune(l.ch); l.nextChar() {
 		// Is Next Character a Period
 		if l.ch == '.' { // YES: Set Token Type and Flags
@@ -123,7 +162,7 @@ func (l *Lexer) nextTokenNumber() token.Token {
 func (l *Lexer) nextTokenIdentifier() token.Token {
 	// MARK Start of Idenitifer
 	start := l.position
-	for l.nextChar(); isValidNextIdentifierRune(l.ch); l.nextChar() {
+	for l.nextChar(); isValidNextIdentifierRune(l.ch) || l.isIdentifierPathSeparator(); l.nextChar() {
 	}
 	// MARK End of Identifier + 1
 	end := l.position
@@ -132,6 +171,45 @@ func (l *Lexer) nextTokenIdentifier() token.Token {
 	return token.Token{Type: token.IDENT, Literal: string(l.input[start:end])}
 }
 
+func (l *Lexer) nextTokenField() token.Token {
+	// Is '@' followed by an Identifier?
+	if !isValidFirstIdentifierRune(l.peekChar(l.readPosition)) { // NO: Illegal Field Reference
+		return newToken(token.ILLEGAL, l.ch)
+	}
+
+	// Skip '@' and Read Field Name
+	l.nextChar()
+	tok := l.nextTokenIdentifier()
+	return token.Token{Type: token.FIELD, Literal: tok.Literal}
+}
+
+func (l *Lexer) nextTokenParam() token.Token {
+	nch := l.peekChar(l.readPosition)
+
+	// Named Parameter ($identifier)?
+	if isValidFirstIdentifierRune(nch) { // YES
+		l.nextChar()
+		tok := l.nextTokenIdentifier()
+
+		// Is Name a Path (i.e. $a.b)?
+		if strings.Contains(tok.Literal, ".") { // YES: Illegal Parameter (Names are Simple Identifiers)
+			return token.Token{Type: token.ILLEGAL, Literal: "$" + tok.Literal}
+		}
+		return token.Token{Type: token.PARAM, Literal: tok.Literal}
+	}
+
+	// Positional Parameter ($1)?
+	if !isDigit(nch) { // NO: Illegal Parameter
+		return newToken(token.ILLEGAL, l.ch)
+	}
+
+	// MARK Start of Position
+	start := l.readPosition
+	for l.nextChar(); isDigit(l.peekChar(l.readPosition)); l.nextChar() {
+	}
+	return token.Token{Type: token.PARAM, Literal: string(l.input[start:l.readPosition])}
+}
+
 func (l *Lexer) nextTokenString() token.Token {
 	// Peek at Next Character
 	nch := l.peekChar(l.readPosition)
@@ -179,6 +257,11 @@ func (l *Lexer) nextTokenString() token.Token {
 	return token.Token{Type: token.STRING, Literal: s.String()}
 }
 
+// Is '.' Separating Identifier Path Components (i.e. owner.email)?
+func (l *Lexer) isIdentifierPathSeparator() bool {
+	return l.ch == '.' && isValidFirstIdentifierRune(l.peekChar(l.readPosition))
+}
+
 func (l *Lexer) isEOL() bool {
 	is := l.position >= len(l.input)
 	return is
@@ -170,7 +170,7 @@ func TestLimiters(t *testing.T) {
 }
 
 func TestIdentifiers(t *testing.T) {
-	input := "and, or, a_b b"
+	input := "and, or, a_b b owner.email a. b.1"
 
 	tests := []struct {
 		expectedType    token.TokenType
@@ -182,6 +182,11 @@ func TestIdentifiers(t *testing.T) {
 		{token.COMMA, ","},
 		{token.IDENT, "a_b"},
 		{token.IDENT, "b"},
+		{token.IDENT, "owner.email"},
+		{token.IDENT, "a"},
+		{token.ILLEGAL, "."},
+		{token.IDENT, "b"},
+		{token.NUMBER, ".1"},
 		{token.EOL, "\x00"},
 	}
 
@@ -304,3 +309,189 @@ func TestValidStrings(t *testing.T) {
 		}
 	}
 }
+
+func TestFieldReferences(t *testing.T) {
+	input := "@created, @a_b @ @1"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.FIELD, "created"},
+		{token.COMMA, ","},
+		{token.FIELD, "a_b"},
+		{token.ILLEGAL, "@"},
+		{token.ILLEGAL, "@"},
+		{token.INT, "1"},
+		{token.EOL, "\x00"},
+	}
+
+	// Create New Lexer (for Input)
+	l := NewLexer(input)
+
+	// Run Tests
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNegativeNumbers(t *testing.T) {
+	input := "-1,-12.5 -.5 - -. -1.2.3"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "-1"},
+		{token.COMMA, ","},
+		{token.NUMBER, "-12.5"},
+		{token.NUMBER, "-.5"},
+		{token.ILLEGAL, "-"},
+		{token.ILLEGAL, "-."},
+		{token.ILLEGAL, "-1.2."},
+		{token.INT, "3"},
+		{token.EOL, "\x00"},
+	}
+
+	// Create New Lexer (for Input)
+	l := NewLexer(input)
+
+	// Run Tests
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestParameters(t *testing.T) {
+	input := "$user, $1 $12) $ $_ $a.b $a_b"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.PARAM, "user"},
+		{token.COMMA, ","},
+		{token.PARAM, "1"},
+		{token.PARAM, "12"},
+		{token.RPAREN, ")"},
+		{token.ILLEGAL, "$"},
+		{token.ILLEGAL, "$"},
+		{token.ILLEGAL, "_"},
+		{token.ILLEGAL, "$a.b"},
+		{token.PARAM, "a_b"},
+		{token.EOL, "\x00"},
+	}
+
+	// Create New Lexer (for Input)
+	l := NewLexer(input)
+
+	// Run Tests
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestSpans(t *testing.T) {
+	input := " eq(name, \"a b\") $1 @f  "
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedStart int
+		expectedEnd   int
+	}{
+		{token.IDENT, 1, 3},
+		{token.LPAREN, 3, 4},
+		{token.IDENT, 4, 8},
+		{token.COMMA, 8, 9},
+		{token.STRING, 10, 15},
+		{token.RPAREN, 15, 16},
+		{token.PARAM, 17, 19},
+		{token.FIELD, 20, 22},
+		{token.EOL, 24, 24},
+	}
+
+	// Create New Lexer (for Input)
+	l := NewLexer(input)
+
+	// Run Tests
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		start, end := l.Span()
+		if start != tt.expectedStart || end != tt.expectedEnd {
+			t.Fatalf("tests[%d] - span wrong. expected=[%d, %d), got=[%d, %d)",
+				i, tt.expectedStart, tt.expectedEnd, start, end)
+		}
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	tests := []struct {
+		input     string
+		maxLength int
+		expected  []token.TokenType
+	}{
+		{"eq(a, 1)", 0, []token.TokenType{token.IDENT, token.LPAREN, token.IDENT, token.COMMA, token.INT, token.RPAREN, token.EOL}},
+		{"eq(a, 1)", 8, []token.TokenType{token.IDENT, token.LPAREN, token.IDENT, token.COMMA, token.INT, token.RPAREN, token.EOL}},
+		{"eq(a, 10)", 8, []token.TokenType{token.ILLEGAL, token.EOL, token.EOL}},
+		{"         ", 8, []token.TokenType{token.ILLEGAL, token.EOL}},
+	}
+
+	for i, tt := range tests {
+		// Create New Lexer (for Input)
+		l := NewLexer(tt.input)
+		l.MaxLength = tt.maxLength
+
+		for j, expected := range tt.expected {
+			tok := l.NextToken()
+
+			if tok.Type != expected {
+				t.Fatalf("tests[%d][%d] - tokentype wrong. expected=%q, got=%q",
+					i, j, expected, tok.Type)
+			}
+
+			// Rejected Input Spans the Whole Input
+			if tok.Type == token.ILLEGAL {
+				if start, end := l.Span(); start != 0 || end != len(tt.input) {
+					t.Fatalf("tests[%d][%d] - span wrong. expected=[0, %d), got=[%d, %d)",
+						i, j, len(tt.input), start, end)
+				}
+			}
+		}
+	}
+}
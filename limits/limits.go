@@ -0,0 +1,85 @@
+package limits
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+  RESOURCE LIMITS
+  - Limits are opt-in: lexer.Lexer, parser.Parser and syntax.SyntaxChecker
+    enforce no limits until one is set, i.e.
+
+      p := parser.NewParser(l)
+      p.Limits = limits.Default()
+
+  - EXCEPT: Nesting is always bounded, so a filter can't exhaust the stack.
+    With no Limits (or MaxDepth 0) the parser and checker stop at
+    STACK_MAX_DEPTH. The checker also keeps its MATCHES pattern bound
+    (SyntaxChecker.MaxPatternLength, 256) while Limits is nil
+  - Use the same Limits for the Parser and the SyntaxChecker. The parser
+    bounds input length, nesting, node count and string length before an AST
+    exists. The checker bounds nesting, strings, pattern operators, wildcards,
+    list sizes and MATCHES pattern length, which also covers ASTs built
+    without the parser
+*/
+
+// Nesting Bound when no Depth Limit is Set (Stack Safety)
+const STACK_MAX_DEPTH = 128
+
+// Error Codes (ast.ParseError.Code and syntax.SyntaxError.Code)
+const (
+	ERR_INPUT_LENGTH   = "INPUT_LENGTH"
+	ERR_DEPTH          = "DEPTH"
+	ERR_NODES          = "NODES"
+	ERR_STRING_LENGTH  = "STRING_LENGTH"
+	ERR_PATTERNS       = "PATTERNS"
+	ERR_PATTERN_LENGTH = "PATTERN_LENGTH"
+	ERR_WILDCARDS      = "WILDCARDS"
+	ERR_LIST_SIZE      = "LIST_SIZE"
+)
+
+// Resource Limits for Untrusted Filters (0 - No Limit)
+type Limits struct {
+	MaxInputLength   int // Characters in Filter Text
+	MaxDepth         int // Function Nesting
+	MaxNodes         int // Functions and Values
+	MaxStringLength  int // Characters in a String Value
+	MaxPatterns      int // Pattern Operators (CONTAINS, ICONTAINS, STARTSWITH, ENDSWITH, MATCHES, SEARCH)
+	MaxPatternLength int // Characters in a MATCHES Regular Expression
+	MaxWildcards     int // Wildcards ('*') in all String Values
+	MaxListSize      int // Values in a List Operator (i.e. IN, HAS_ANY, HAS_ALL)
+}
+
+// Limits for Filters from Untrusted Sources
+func Default() *Limits {
+	l := &Limits{
+		MaxInputLength:   4096,
+		MaxDepth:         32,
+		MaxNodes:         512,
+		MaxStringLength:  1024,
+		MaxPatterns:      8,
+		MaxPatternLength: 256,
+		MaxWildcards:     32,
+		MaxListSize:      100,
+	}
+	return l
+}
+
+// Effective Nesting Limit (l can be nil)
+func MaxDepth(l *Limits) int {
+	if l != nil && l.MaxDepth > 0 {
+		return l.MaxDepth
+	}
+	return STACK_MAX_DEPTH
+}
+
+// Is Value over Limit?
+func Exceeds(value int, limit int) bool {
+	return limit > 0 && value > limit
+}
@@ -0,0 +1,86 @@
+package limits
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+)
+
+func TestExceeds(t *testing.T) {
+	tests := []struct {
+		value    int
+		limit    int
+		expected bool
+	}{
+		{0, 0, false},
+		{1000, 0, false},
+		{9, 10, false},
+		{10, 10, false},
+		{11, 10, true},
+		{1, -1, false},
+	}
+
+	for i, tt := range tests {
+		got := Exceeds(tt.value, tt.limit)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - exceeds wrong. expected=%t, got=%t", i, tt.expected, got)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	l := Default()
+
+	tests := []struct {
+		name  string
+		value int
+	}{
+		{"MaxInputLength", l.MaxInputLength},
+		{"MaxDepth", l.MaxDepth},
+		{"MaxNodes", l.MaxNodes},
+		{"MaxStringLength", l.MaxStringLength},
+		{"MaxPatterns", l.MaxPatterns},
+		{"MaxPatternLength", l.MaxPatternLength},
+		{"MaxWildcards", l.MaxWildcards},
+		{"MaxListSize", l.MaxListSize},
+	}
+
+	for i, tt := range tests {
+		if tt.value <= 0 {
+			t.Fatalf("tests[%d] - %s not limited. got=%d", i, tt.name, tt.value)
+		}
+	}
+
+	// Each Call Returns a Separate Copy
+	Default().MaxDepth = 1
+	if l.MaxDepth == 1 || Default().MaxDepth == 1 {
+		t.Fatalf("Default() returned shared limits")
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	tests := []struct {
+		limits   *Limits
+		expected int
+	}{
+		{nil, STACK_MAX_DEPTH},
+		{&Limits{}, STACK_MAX_DEPTH},
+		{&Limits{MaxDepth: 10}, 10},
+		{Default(), 32},
+	}
+
+	for i, tt := range tests {
+		got := MaxDepth(tt.limits)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - depth wrong. expected=%d, got=%d", i, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+package lsp
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+/*
+  EMBEDDED FILTERS
+  A document is a single filter, unless its URI ends in ".yaml", ".yml" or
+  ".json". Then every value of a filter key (Server.Keys) is a filter:
+
+    rules:
+      - name: active
+        filter: and(eq(state, "active"), gt(created, 1000))
+      - name: old
+        filter: |
+          and(
+            eq(state, "archived"),
+            lt(created, 1000)
+          )
+
+  - YAML: plain, 'single' and "double" quoted values (on the key's line)
+    and block values (| or >). Flow mappings ({filter: ...}) and quoted
+    values that continue on the next line are not extracted
+  - JSON: string values, at any depth
+
+  Filters are unescaped before use, and character offsets in the filter
+  are mapped back to the document (so ranges cover the escaped text).
+*/
+
+// Default Filter Keys
+var DEFAULT_KEYS = []string{"filter"}
+
+// Value Styles (How the Filter is Written in the Document)
+const (
+	STYLE_DOCUMENT = iota // Whole Document
+	STYLE_PLAIN           // YAML Plain Value
+	STYLE_SINGLE          // YAML Single Quoted Value
+	STYLE_DOUBLE          // YAML Double Quoted Value or JSON String
+	STYLE_BLOCK           // YAML Block Value
+)
+
+// Filter Embedded in a Document
+type region struct {
+	text    string // Filter (Unescaped)
+	offsets []int  // Document Offset of each Filter Character (and of the End of the Filter)
+	start   int    // Document Range of the Value [start, end) (including Quotes)
+	end     int
+	style   int    // STYLE_*
+	indent  string // Block Value Indentation
+}
+
+// Document Offset of a Filter Offset
+func (r *region) toDocument(offset int) int {
+	return r.offsets[offset]
+}
+
+// Filter Offset of a Document Offset (-1 - Outside the Filter)
+func (r *region) toFilter(offset int) int {
+	if offset < r.offsets[0] || offset > r.offsets[len(r.offsets)-1] {
+		return -1
+	}
+
+	i := 0
+	for i+1 < len(r.offsets) && r.offsets[i+1] <= offset {
+		i++
+	}
+	return i
+}
+
+// Filters in a Document
+func regions(uri string, text []rune, keys []string) []*region {
+	name := strings.ToLower(uri)
+	switch {
+	case strings.HasSuffix(name, ".yaml"), strings.HasSuffix(name, ".yml"):
+		return yamlRegions(text, keys)
+	case strings.HasSuffix(name, ".json"):
+		return jsonRegions(text, keys)
+	}
+
+	// Whole Document is the Filter
+	offsets := make([]int, len(text)+1)
+	for i := range offsets {
+		offsets[i] = i
+	}
+	return []*region{{text: string(text), offsets: offsets, start: 0, end: len(text), style: STYLE_DOCUMENT}}
+}
+
+// Region Containing a Document Offset (nil - None)
+func regionAt(rs []*region, offset int) (*region, int) {
+	for _, r := range rs {
+		if fo := r.toFilter(offset); fo >= 0 {
+			return r, fo
+		}
+	}
+	return nil, -1
+}
+
+func isKey(key string, keys []string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// YAML //
+
+// Document Line [start, end) (end excludes "\n" and "\r")
+type line struct {
+	start int
+	end   int
+}
+
+func lines(text []rune) []line {
+	l := make([]line, 0)
+	start := 0
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && text[i] != '\n' {
+			continue
+		}
+
+		end := i
+		if end > start && text[end-1] == '\r' {
+			end--
+		}
+		l = append(l, line{start, end})
+		start = i + 1
+	}
+	return l
+}
+
+// Leading Spaces in Line
+func indentation(text []rune, ln line) int {
+	i := ln.start
+	for i < ln.end && text[i] == ' ' {
+		i++
+	}
+	return i - ln.start
+}
+
+func yamlRegions(text []rune, keys []string) []*region {
+	rs := make([]*region, 0)
+	ls := lines(text)
+
+	for li := 0; li < len(ls); li++ {
+		ln := ls[li]
+
+		// Skip Indentation and Sequence Entries ("- ")
+		i := ln.start + indentation(text, ln)
+		for i+1 < ln.end && text[i] == '-' && text[i+1] == ' ' {
+			for i++; i < ln.end && text[i] == ' '; i++ {
+			}
+		}
+		column := i - ln.start
+
+		// Key?
+		colon := -1
+		for j := i; j < ln.end; j++ {
+			if text[j] == ':' && (j+1 == ln.end || text[j+1] == ' ' || text[j+1] == '\t') {
+				colon = j
+				break
+			}
+		}
+		if colon < 0 { // NO
+			continue
+		}
+
+		// Filter Key?
+		key := strings.TrimSpace(string(text[i:colon]))
+		if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') && key[len(key)-1] == key[0] {
+			key = key[1 : len(key)-1]
+		}
+		if !isKey(key, keys) { // NO
+			continue
+		}
+
+		// Skip to Value
+		v := colon + 1
+		for v < ln.end && (text[v] == ' ' || text[v] == '\t') {
+			v++
+		}
+		if v == ln.end || text[v] == '#' { // No Value on the Line
+			continue
+		}
+
+		var r *region
+		switch text[v] {
+		case '|', '>':
+			r, li = yamlBlock(text, ls, li, column)
+		case '\'':
+			r = yamlSingle(text, v, ln.end)
+		case '"':
+			r = unquote(text, v, ln.end)
+		default:
+			r = yamlPlain(text, v, ln.end)
+		}
+
+		if r != nil {
+			rs = append(rs, r)
+		}
+	}
+
+	return rs
+}
+
+// Plain Value (up to a Comment or the End of Line)
+func yamlPlain(text []rune, start int, end int) *region {
+	for i := start + 1; i < end; i++ {
+		if text[i] == '#' && (text[i-1] == ' ' || text[i-1] == '\t') {
+			end = i
+			break
+		}
+	}
+
+	for end > start && (text[end-1] == ' ' || text[end-1] == '\t') {
+		end--
+	}
+
+	offsets := make([]int, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		offsets = append(offsets, i)
+	}
+	return &region{text: string(text[start:end]), offsets: offsets, start: start, end: end, style: STYLE_PLAIN}
+}
+
+// Single Quoted Value (a Quote is Escaped by Doubling it)
+func yamlSingle(text []rune, start int, end int) *region {
+	value := make([]rune, 0)
+	offsets := make([]int, 0)
+
+	for i := start + 1; i < end; i++ {
+		if text[i] == '\'' {
+			// Escaped Quote?
+			if i+1 < end && text[i+1] == '\'' { // YES
+				value = append(value, '\'')
+				offsets = append(offsets, i)
+				i++
+				continue
+			}
+
+			offsets = append(offsets, i)
+			return &region{text: string(value), offsets: offsets, start: start, end: i + 1, style: STYLE_SINGLE}
+		}
+
+		value = append(value, text[i])
+		offsets = append(offsets, i)
+	}
+
+	// Not Closed on the Line
+	return nil
+}
+
+// Block Value (Lines Indented Deeper than the Key, returns the Last Line Used)
+func yamlBlock(text []rune, ls []line, li int, column int) (*region, int) {
+	// Block Indentation (1st Non Blank Line)
+	indent := -1
+	for j := li + 1; j < len(ls); j++ {
+		if n := indentation(text, ls[j]); ls[j].start+n < ls[j].end {
+			indent = n
+			break
+		}
+	}
+
+	if indent <= column { // Empty Block
+		return nil, li
+	}
+
+	// Content Lines (Blank or Indented)
+	last := li
+	for j := li + 1; j < len(ls); j++ {
+		n := indentation(text, ls[j])
+		if ls[j].start+n < ls[j].end && n < indent {
+			break
+		}
+		if ls[j].start+n < ls[j].end {
+			last = j
+		}
+	}
+
+	value := make([]rune, 0)
+	offsets := make([]int, 0)
+	for j := li + 1; j <= last; j++ {
+		// Line Break between Lines
+		if j > li+1 {
+			value = append(value, '\n')
+			offsets = append(offsets, ls[j-1].end)
+		}
+
+		for i := ls[j].start + indent; i < ls[j].end; i++ {
+			value = append(value, text[i])
+			offsets = append(offsets, i)
+		}
+	}
+	offsets = append(offsets, ls[last].end)
+
+	start := ls[li+1].start + indent
+	r := &region{text: string(value), offsets: offsets, start: start, end: ls[last].end, style: STYLE_BLOCK, indent: strings.Repeat(" ", indent)}
+	return r, last
+}
+
+// JSON //
+
+func jsonRegions(text []rune, keys []string) []*region {
+	rs := make([]*region, 0)
+
+	for i := 0; i < len(text); i++ {
+		if text[i] != '"' {
+			continue
+		}
+
+		// String
+		s := unquote(text, i, len(text))
+		if s == nil { // Not Closed
+			break
+		}
+		i = s.end - 1
+
+		// Object Key?
+		j := skipSpaces(text, s.end)
+		if j == len(text) || text[j] != ':' { // NO
+			continue
+		}
+
+		// Filter Key with a String Value?
+		v := skipSpaces(text, j+1)
+		if !isKey(s.text, keys) || v == len(text) || text[v] != '"' { // NO
+			continue
+		}
+
+		r := unquote(text, v, len(text))
+		if r == nil {
+			break
+		}
+		rs = append(rs, r)
+		i = r.end - 1
+	}
+
+	return rs
+}
+
+func skipSpaces(text []rune, i int) int {
+	for i < len(text) && (text[i] == ' ' || text[i] == '\t' || text[i] == '\r' || text[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// Double Quoted String starting at text[start] (nil - Not Closed before end)
+// NOTE: JSON escapes are a subset of YAML double quoted escapes
+func unquote(text []rune, start int, end int) *region {
+	value := make([]rune, 0)
+	offsets := make([]int, 0)
+
+	for i := start + 1; i < end; i++ {
+		switch text[i] {
+		case '"':
+			offsets = append(offsets, i)
+			return &region{text: string(value), offsets: offsets, start: start, end: i + 1, style: STYLE_DOUBLE}
+		case '\\':
+			if i+1 == end {
+				return nil
+			}
+
+			offsets = append(offsets, i)
+			r, n := unescape(text[i+1 : end])
+			value = append(value, r)
+			i += n
+		default:
+			value = append(value, text[i])
+			offsets = append(offsets, i)
+		}
+	}
+
+	// Not Closed
+	return nil
+}
+
+// Escaped Character (text follows the '\', returns the Character and the Runes Used)
+func unescape(text []rune) (rune, int) {
+	switch text[0] {
+	case 'n':
+		return '\n', 1
+	case 't':
+		return '\t', 1
+	case 'r':
+		return '\r', 1
+	case 'b':
+		return '\b', 1
+	case 'f':
+		return '\f', 1
+	case '0':
+		return 0, 1
+	case 'x':
+		return hex(text, 2)
+	case 'u':
+		r, n := hex(text, 4)
+
+		// Surrogate Pair?
+		if utf16.IsSurrogate(r) && len(text) >= n+6 && text[n] == '\\' && text[n+1] == 'u' { // YES
+			if r2, n2 := hex(text[n+1:], 4); n2 == 5 {
+				if c := utf16.DecodeRune(r, r2); c != unicode.ReplacementChar {
+					return c, n + 1 + n2
+				}
+			}
+		}
+		return r, n
+	}
+
+	// Character Escapes Itself (i.e. \" \\ \/)
+	return text[0], 1
+}
+
+// Hex Escape (text[0] is the Escape Letter)
+func hex(text []rune, digits int) (rune, int) {
+	if len(text) <= digits {
+		return text[0], 1
+	}
+
+	v, err := strconv.ParseUint(string(text[1:1+digits]), 16, 32)
+	if err != nil {
+		return text[0], 1
+	}
+	return rune(v), 1 + digits
+}
+
+// Write a Filter in the Region's Style (Block Values can span several Lines)
+func (r *region) encode(filter string) string {
+	switch r.style {
+	case STYLE_SINGLE:
+		return "'" + strings.ReplaceAll(filter, "'", "''") + "'"
+	case STYLE_DOUBLE:
+		return "\"" + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(filter) + "\""
+	case STYLE_BLOCK:
+		return strings.ReplaceAll(filter, "\n", "\n"+r.indent)
+	}
+	return filter
+}
@@ -0,0 +1,226 @@
+package lsp
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRegions(t *testing.T) {
+	tests := []struct {
+		uri      string
+		input    string
+		expected []string // Filter Text of each Region
+	}{
+		{"file:///a.filter", `eq(a, 1)`, []string{`eq(a, 1)`}},
+		{"file:///a.yaml", "filter: eq(a, 1)", []string{`eq(a, 1)`}},
+		{"file:///a.yml", "filter: eq(a, 1)   # comment", []string{`eq(a, 1)`}},
+		{"file:///a.yaml", "name: x\nfilter: eq(a, 1)\nother: eq(b, 2)", []string{`eq(a, 1)`}},
+		{"file:///a.yaml", "rules:\n  - filter: eq(a, 1)\n  - name: y\n    filter: eq(b, 2)", []string{`eq(a, 1)`, `eq(b, 2)`}},
+		{"file:///a.yaml", `filter: 'eq(a, ''x'')'`, []string{`eq(a, 'x')`}},
+		{"file:///a.yaml", `filter: "eq(a, \"x\")"`, []string{`eq(a, "x")`}},
+		{"file:///a.yaml", `"filter": eq(a, 1)`, []string{`eq(a, 1)`}},
+		{"file:///a.yaml", "filter: |\n  and(\n    eq(a, 1),\n\n    eq(b, 2)\n  )\nname: x", []string{"and(\n  eq(a, 1),\n\n  eq(b, 2)\n)"}},
+		{"file:///a.yaml", "filter: >-\r\n  eq(a, 1)\r\n", []string{"eq(a, 1)"}},
+		{"file:///a.yaml", "filter: |\nname: x", []string{}},
+		{"file:///a.yaml", "filter: \"eq(a, 1)\n  \"", []string{}},
+		{"file:///a.yaml", "filter:\n  eq(a, 1)", []string{}},
+		{"file:///a.json", `{"filter": "eq(a, \"x\")"}`, []string{`eq(a, "x")`}},
+		{"file:///a.json", `{"rules": [{"filter": "eq(a, 1)"}, {"name": "filter", "filter": "eq(b, 2)"}]}`, []string{`eq(a, 1)`, `eq(b, 2)`}},
+		{"file:///a.json", `{"filter": 1, "x": {"filter" : "eq(a, \"😀\")"}}`, []string{"eq(a, \"\U0001F600\")"}},
+		{"file:///a.json", `{"filter": "eq(a, 1)`, []string{}},
+	}
+
+	for i, tt := range tests {
+		rs := regions(tt.uri, []rune(tt.input), DEFAULT_KEYS)
+		if len(rs) != len(tt.expected) {
+			t.Fatalf("tests[%d] - regions wrong. expected=%d, got=%d", i, len(tt.expected), len(rs))
+		}
+
+		for j, r := range rs {
+			if r.text != tt.expected[j] {
+				t.Fatalf("tests[%d] - region[%d] text wrong. expected=%q, got=%q", i, j, tt.expected[j], r.text)
+			}
+			if len(r.offsets) != len([]rune(r.text))+1 {
+				t.Fatalf("tests[%d] - region[%d] offsets wrong. expected=%d, got=%d", i, j, len([]rune(r.text))+1, len(r.offsets))
+			}
+		}
+	}
+}
+
+// Filter Offsets Map to the Escaped Document Text
+func TestRegionOffsets(t *testing.T) {
+	tests := []struct {
+		uri      string
+		input    string
+		filter   int    // Filter Offset
+		expected string // Document Text from the Mapped Offset
+	}{
+		{"file:///a.yaml", "filter: eq(a, 1)", 3, "a, 1)"},
+		{"file:///a.yaml", `filter: 'eq(a, ''x'')'`, 6, `''x'')'`},
+		{"file:///a.yaml", `filter: 'eq(a, ''x'')'`, 7, `x'')'`},
+		{"file:///a.yaml", `filter: "eq(a, \"x\")"`, 7, `x\")"`},
+		{"file:///a.yaml", "filter: |\n  and(\n    eq(a, 1))", 7, "eq(a, 1))"},
+		{"file:///a.json", `{"filter": "eq(a, \"x\")"}`, 6, `\"x\")"}`},
+		{"file:///a.json", `{"filter": "eq(a, \"x\")"}`, 10, `"}`},
+	}
+
+	for i, tt := range tests {
+		text := []rune(tt.input)
+		r := regions(tt.uri, text, DEFAULT_KEYS)[0]
+
+		offset := r.toDocument(tt.filter)
+		if got := string(text[offset:]); got != tt.expected {
+			t.Fatalf("tests[%d] - offset wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+
+		// Round Trip
+		if got := r.toFilter(offset); got != tt.filter {
+			t.Fatalf("tests[%d] - filter offset wrong. expected=%d, got=%d", i, tt.filter, got)
+		}
+	}
+
+	// Outside the Filter
+	r := regions("file:///a.yaml", []rune(`filter: "eq(a, 1)"`), DEFAULT_KEYS)[0]
+	if r.toFilter(0) != -1 || r.toFilter(8) != -1 {
+		t.Fatalf("offsets outside the filter were mapped")
+	}
+}
+
+func TestEmbeddedDiagnostics(t *testing.T) {
+	tests := []struct {
+		uri      string
+		input    string
+		expected []string // Diagnostic Message and the Document Text it Covers
+	}{
+		{"file:///a.yaml", "rules:\n  - filter: eq(state, \"a\")\n  - filter: gt(zz, 1)", []string{"Function [GT] Field [zz] is not recognized|gt(zz, 1)"}},
+		{"file:///a.yaml", `filter: "and(eq(state, \"a\"), gt(created, \"x\"))"`, []string{`Function [GT] Field [created] expects a value of type [INT] not [STRING]|gt(created, \"x\")`}},
+		{"file:///a.yaml", "filter: |\n  and(\n    eq(state, 1),\n    gt(created, 1))", []string{"Function [EQ] Field [state] expects a value of type [STRING] not [INT]|eq(state, 1)"}},
+		{"file:///a.json", `{"a": {"filter": "eq(state, \"a\""}, "b": {"filter": "lt(created, 1)"}}`, []string{`FUNCTION: expecting function ")"|`}},
+		{"file:///a.json", `{"filter": "eq(state, \"a\")", "other": "eq(zz, 1)"}`, []string{}},
+	}
+
+	s := NewServer(nil, nil)
+	s.Schema = testSchema()
+	for i, tt := range tests {
+		text := []rune(tt.input)
+		d := s.diagnostics(tt.uri, tt.input)
+		if len(d) != len(tt.expected) {
+			t.Fatalf("tests[%d] - diagnostics wrong. expected=%d, got=%d", i, len(tt.expected), len(d))
+		}
+
+		for j, dg := range d {
+			got := dg.Message + "|" + string(text[toOffset(text, dg.Range.Start):toOffset(text, dg.Range.End)])
+			if got != tt.expected[j] {
+				t.Fatalf("tests[%d] - diagnostic[%d] wrong. expected=%q, got=%q", i, j, tt.expected[j], got)
+			}
+		}
+	}
+}
+
+func TestEmbeddedSession(t *testing.T) {
+	yaml := "rules:\n  - name: a\n    filter: and(eq(state,\"active\"),gt(created,1))\n  - name: b\n    filter: 'eq( state, \"it''s\" )'\n"
+	json := `{"rules": [{"where": "eq(state,\"a\")"}]}`
+
+	in := script([]step{
+		{1, "initialize", `{}`},
+		{0, "textDocument/didOpen", `{"textDocument":{"uri":"file:///a.yaml","version":1,"text":` + quote(yaml) + `}}`},
+		{2, "textDocument/completion", `{"textDocument":{"uri":"file:///a.yaml"},"position":{"line":2,"character":19}}`},
+		{3, "textDocument/completion", `{"textDocument":{"uri":"file:///a.yaml"},"position":{"line":1,"character":5}}`},
+		{4, "textDocument/hover", `{"textDocument":{"uri":"file:///a.yaml"},"position":{"line":2,"character":21}}`},
+		{5, "textDocument/formatting", `{"textDocument":{"uri":"file:///a.yaml"},"options":{"tabSize":2,"insertSpaces":true}}`},
+		{6, "shutdown", ""},
+		{0, "exit", ""},
+	})
+
+	var out bytes.Buffer
+	s := NewServer(in, &out)
+	s.Schema = testSchema()
+	if code := s.Run(); code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d", code)
+	}
+
+	rs := replies(t, &out)
+	if len(rs) != 7 {
+		t.Fatalf("number of replies wrong. expected=7, got=%d", len(rs))
+	}
+
+	tests := []struct {
+		reply            int
+		expectedContains string
+	}{
+		{1, `"diagnostics":[]`},
+		{2, `"label":"state","kind":5,"detail":"STRING","textEdit":{"range":{"start":{"line":2,"character":19},"end":{"line":2,"character":19}}`},
+		{3, `"items":[]`},
+		{4, `field `},
+		{5, `"newText":"and(eq(state, \"active\"), gt(created, 1))"`},
+		{5, `{"range":{"start":{"line":4,"character":12},"end":{"line":4,"character":34}},"newText":"'eq(state, \"it''s\")'"}`},
+	}
+
+	for i, tt := range tests {
+		body := string(rs[tt.reply].Result) + string(rs[tt.reply].Params)
+		if !strings.Contains(body, tt.expectedContains) {
+			t.Fatalf("tests[%d] - content wrong. expected=%q, got=%q", i, tt.expectedContains, body)
+		}
+	}
+
+	// Keys from the Initialization Options
+	in = script([]step{
+		{1, "initialize", `{"initializationOptions":{"keys":["where"]}}`},
+		{0, "textDocument/didOpen", `{"textDocument":{"uri":"file:///b.json","version":1,"text":` + quote(json) + `}}`},
+		{2, "textDocument/formatting", `{"textDocument":{"uri":"file:///b.json"},"options":{"tabSize":2,"insertSpaces":true}}`},
+		{0, "exit", ""},
+	})
+
+	out.Reset()
+	NewServer(in, &out).Run()
+
+	rs = replies(t, &out)
+	if len(rs) != 3 || !strings.Contains(string(rs[2].Result), `"newText":"\"eq(state, \\\"a\\\")\""`) {
+		t.Fatalf("json formatting wrong. got=%d replies", len(rs))
+	}
+}
+
+// Formatted Block Values keep their Indentation
+func TestBlockFormatting(t *testing.T) {
+	input := "filter: |\n    and(eq(state, \"active\"), and(gt(created, 1000), lt(created, 2000000)), eq(state, \"archived\"))\nname: x\n"
+	expected := "and(\n      eq(state, \"active\"),\n      and(gt(created, 1000), lt(created, 2000000)),\n      eq(state, \"archived\")\n    )"
+
+	s := NewServer(nil, nil)
+	s.documents["file:///a.yaml"] = input
+
+	p := formattingParams{}
+	p.TextDocument.URI = "file:///a.yaml"
+	p.Options.TabSize = 2
+	p.Options.InsertSpaces = true
+
+	edits := s.formatting(p)
+	if len(edits) != 1 {
+		t.Fatalf("edits wrong. expected=1, got=%d", len(edits))
+	}
+
+	if edits[0].NewText != expected {
+		t.Fatalf("formatting wrong. expected=%q, got=%q", expected, edits[0].NewText)
+	}
+
+	// Edit Replaces the Block Content (after the Indentation of the 1st Line)
+	if r := edits[0].Range; r.Start.Line != 1 || r.Start.Character != 4 || r.End.Line != 1 {
+		t.Fatalf("range wrong. got=%+v", r)
+	}
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,188 @@
+package lsp
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"encoding/json"
+	"unicode/utf16"
+)
+
+// Subset of the Language Server Protocol used by the Server
+
+// JSON-RPC Error Codes
+const (
+	ERR_PARSE            = -32700
+	ERR_INVALID_REQUEST  = -32600
+	ERR_METHOD_NOT_FOUND = -32601
+	ERR_INVALID_PARAMS   = -32602
+	ERR_NOT_INITIALIZED  = -32002
+)
+
+// LSP Enumerations
+const (
+	SYNC_FULL = 1
+
+	SEVERITY_ERROR = 1
+
+	COMPLETION_FUNCTION       = 3
+	COMPLETION_FIELD          = 5
+	COMPLETION_MODULE         = 9
+	COMPLETION_VALUE          = 12
+	COMPLETION_TYPE_PARAMETER = 25
+)
+
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"` // UTF-16 Code Units
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+type CompletionItem struct {
+	Label    string    `json:"label"`
+	Kind     int       `json:"kind"`
+	Detail   string    `json:"detail,omitempty"`
+	TextEdit *TextEdit `json:"textEdit,omitempty"`
+}
+
+type CompletionList struct {
+	IsIncomplete bool              `json:"isIncomplete"`
+	Items        []*CompletionItem `json:"items"`
+}
+
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+type textDocumentItem struct {
+	URI     string `json:"uri"`
+	Text    string `json:"text"`
+	Version int    `json:"version"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type initializeParams struct {
+	InitializationOptions struct {
+		Schema string   `json:"schema"` // OPTIONAL: Schema File
+		Keys   []string `json:"keys"`   // OPTIONAL: Keys of Filters in YAML or JSON Documents
+	} `json:"initializationOptions"`
+}
+
+type didOpenParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type didChangeParams struct {
+	TextDocument   textDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type didCloseParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type positionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type formattingParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Options      struct {
+		TabSize      int  `json:"tabSize"`
+		InsertSpaces bool `json:"insertSpaces"`
+	} `json:"options"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string        `json:"uri"`
+	Diagnostics []*Diagnostic `json:"diagnostics"`
+}
+
+// Convert Character Offset to LSP Position
+func toPosition(text []rune, offset int) Position {
+	p := Position{}
+	for _, r := range text[:offset] {
+		if r == '\n' {
+			p.Line++
+			p.Character = 0
+		} else {
+			p.Character += len(utf16.Encode([]rune{r}))
+		}
+	}
+	return p
+}
+
+// Convert LSP Position to Character Offset
+func toOffset(text []rune, p Position) int {
+	line, character := 0, 0
+	for i, r := range text {
+		if line == p.Line && character >= p.Character {
+			return i
+		}
+
+		if r == '\n' {
+			// Position past End of Line?
+			if line == p.Line { // YES: Clamp to End of Line
+				return i
+			}
+			line++
+			character = 0
+		} else if line == p.Line {
+			character += len(utf16.Encode([]rune{r}))
+		}
+	}
+	return len(text)
+}
+
+func toRange(text []rune, start int, end int) Range {
+	return Range{Start: toPosition(text, start), End: toPosition(text, end)}
+}
@@ -0,0 +1,469 @@
+package lsp
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/objectvault/filter-parser/completion"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+	"github.com/objectvault/filter-parser/printer"
+	"github.com/objectvault/filter-parser/schema"
+	"github.com/objectvault/filter-parser/syntax"
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  LANGUAGE SERVER
+  JSON-RPC over a stream ("Content-Length" framed). A document is a single
+  filter, or a YAML or JSON file with embedded filters (see embedded.go):
+  - diagnostics: tolerant parser, then the Syntax Checker
+  - completion: completion.Completer (fields from the Schema)
+  - hover: function signatures and documentation, field types
+  - formatting: canonical form (printer.Printer), embedded filters are
+    written back in the same style (single line unless in a block value)
+
+  The server is single threaded, requests are answered in order.
+*/
+
+// Function Documentation (Hover)
+var docs = map[string]string{
+	"AND":        "Both filters match.",
+	"OR":         "Either filter matches.",
+	"NOT":        "The filter does not match.",
+	"EQ":         "Field is equal to the value.",
+	"NEQ":        "Field is not equal to the value.",
+	"GT":         "Field is greater than the value.",
+	"GTE":        "Field is greater than or equal to the value.",
+	"LT":         "Field is less than the value.",
+	"LTE":        "Field is less than or equal to the value.",
+	"BETWEEN":    "Field is between low and high (inclusive).",
+	"IN":         "Field is equal to one of the values.",
+	"CONTAINS":   "Field matches the pattern ('*' is a wildcard).",
+	"STARTSWITH": "Field starts with the prefix.",
+	"ENDSWITH":   "Field ends with the suffix.",
+	"ICONTAINS":  "Field matches the pattern, ignoring case.",
+	"IEQ":        "Field is equal to the value, ignoring case.",
+	"MATCHES":    "Field matches the regular expression.",
+	"EXISTS":     "Field has a value.",
+	"MISSING":    "Field has no value.",
+	"HAS":        "Collection field includes the value.",
+	"HAS_ANY":    "Collection field includes any of the values.",
+	"HAS_ALL":    "Collection field includes all of the values.",
+	"SEARCH":     "Full text search of the terms over the fields.",
+	"NEAR":       "Point field is within radius of (lat, lon).",
+	"WITHIN_BOX": "Point field is inside the box (lat1, lon1) to (lat2, lon2).",
+	"MACRO":      "Expands the named filter fragment.",
+}
+
+// Server Object
+type Server struct {
+	Schema      *schema.Schema // OPTIONAL: Known Fields
+	Keys        []string       // OPTIONAL: Keys of Filters in YAML or JSON Documents (nil - DEFAULT_KEYS)
+	Log         io.Writer      // OPTIONAL: Server Log (never the Output Stream)
+	in          *bufio.Reader
+	out         io.Writer
+	documents   map[string]string
+	initialized bool
+	shutdown    bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	s := &Server{in: bufio.NewReader(in), out: out, documents: make(map[string]string)}
+	return s
+}
+
+// Serve Requests until "exit" or End of Input (returns Process Exit Code)
+func (s *Server) Run() int {
+	for {
+		m, err := s.read()
+		if err != nil {
+			if err != io.EOF {
+				s.log("read: %s", err.Error())
+			}
+			return 1
+		}
+
+		// Invalid Message (Already Answered)?
+		if m == nil { // YES: Skip it
+			continue
+		}
+
+		// Exit Notification?
+		if m.Method == "exit" { // YES: Exit Code depends on Shutdown
+			if s.shutdown {
+				return 0
+			}
+			return 1
+		}
+
+		s.handle(m)
+	}
+}
+
+func (s *Server) handle(m *message) {
+	// Server Initialized?
+	if !s.initialized && m.Method != "initialize" { // NO
+		if m.ID != nil {
+			s.reply(m.ID, nil, &responseError{Code: ERR_NOT_INITIALIZED, Message: "Server not initialized"})
+		}
+		return
+	}
+
+	var result interface{}
+	var e *responseError
+
+	switch m.Method {
+	case "initialize":
+		result, e = s.initialize(m.Params)
+	case "initialized":
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if e = decode(m.Params, &p); e == nil {
+			s.update(p.TextDocument.URI, p.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var p didChangeParams
+		if e = decode(m.Params, &p); e == nil && len(p.ContentChanges) > 0 {
+			// Full Sync: Last Change is the Document
+			s.update(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		var p didCloseParams
+		if e = decode(m.Params, &p); e == nil {
+			delete(s.documents, p.TextDocument.URI)
+			s.publish(p.TextDocument.URI, make([]*Diagnostic, 0))
+		}
+	case "textDocument/completion":
+		var p positionParams
+		if e = decode(m.Params, &p); e == nil {
+			result = s.completion(p)
+		}
+	case "textDocument/hover":
+		var p positionParams
+		if e = decode(m.Params, &p); e == nil {
+			result = s.hover(p)
+		}
+	case "textDocument/formatting":
+		var p formattingParams
+		if e = decode(m.Params, &p); e == nil {
+			result = s.formatting(p)
+		}
+	default:
+		// Unknown Notifications are Ignored
+		if m.ID != nil {
+			e = &responseError{Code: ERR_METHOD_NOT_FOUND, Message: fmt.Sprintf("Method [%s] not supported", m.Method)}
+		}
+	}
+
+	// Request?
+	if m.ID != nil { // YES: Reply
+		s.reply(m.ID, result, e)
+	}
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, *responseError) {
+	var p initializeParams
+	if e := decode(params, &p); e != nil {
+		return nil, e
+	}
+
+	// Schema File Given (and none Loaded)?
+	if s.Schema == nil && p.InitializationOptions.Schema != "" { // YES
+		sc, e := schema.LoadFile(p.InitializationOptions.Schema)
+		if e != nil {
+			return nil, &responseError{Code: ERR_INVALID_PARAMS, Message: e.ToString()}
+		}
+		s.Schema = sc
+	}
+
+	// Filter Keys Given (and none Set)?
+	if s.Keys == nil && len(p.InitializationOptions.Keys) > 0 { // YES
+		s.Keys = p.InitializationOptions.Keys
+	}
+
+	s.initialized = true
+	result := map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":           SYNC_FULL,
+			"hoverProvider":              true,
+			"documentFormattingProvider": true,
+			"completionProvider": map[string]interface{}{
+				"triggerCharacters": []string{"(", ",", "@", "\""},
+			},
+		},
+		"serverInfo": map[string]string{"name": "filter-lsp"},
+	}
+	return result, nil
+}
+
+// Store Document and Publish its Diagnostics
+func (s *Server) update(uri string, text string) {
+	s.documents[uri] = text
+	s.publish(uri, s.diagnostics(uri, text))
+}
+
+// Filters in Document
+func (s *Server) regions(uri string, text []rune) []*region {
+	keys := s.Keys
+	if keys == nil {
+		keys = DEFAULT_KEYS
+	}
+	return regions(uri, text, keys)
+}
+
+func (s *Server) diagnostics(uri string, text string) []*Diagnostic {
+	runes := []rune(text)
+	diagnostics := make([]*Diagnostic, 0)
+
+	for _, r := range s.regions(uri, runes) {
+		for _, p := range s.problems(r.text) {
+			rg := toRange(runes, r.toDocument(p.start), r.toDocument(p.end))
+			diagnostics = append(diagnostics, &Diagnostic{Range: rg, Severity: SEVERITY_ERROR, Source: "filter", Message: p.message})
+		}
+	}
+
+	return diagnostics
+}
+
+// Filter Problem (Character Range in the Filter)
+type problem struct {
+	start   int
+	end     int
+	message string
+}
+
+func (s *Server) problems(text string) []*problem {
+	problems := make([]*problem, 0)
+
+	// Parse Errors?
+	f, errors := parser.NewParser(lexer.NewLexer(text)).ParseFilterTolerant()
+	for _, pe := range errors {
+		problems = append(problems, &problem{pe.Start, pe.End, pe.Message})
+	}
+
+	if len(problems) > 0 { // YES: Skip Syntax Checker
+		return problems
+	}
+
+	c := syntax.NewSyntaxChecker(f)
+	c.Schema = s.Schema
+	if e := c.Verify(); e != nil {
+		// Syntax Errors cover the Function that Failed (Filter if Unknown)
+		start, end := 0, len([]rune(text))
+		if e.Node != nil && e.Node.End > e.Node.Start {
+			start, end = e.Node.Start, e.Node.End
+		}
+		problems = append(problems, &problem{start, end, e.ToString()})
+	}
+
+	return problems
+}
+
+func (s *Server) completion(p positionParams) *CompletionList {
+	text := []rune(s.documents[p.TextDocument.URI])
+	l := &CompletionList{Items: make([]*CompletionItem, 0)}
+
+	// Cursor in a Filter?
+	rg, offset := regionAt(s.regions(p.TextDocument.URI, text), toOffset(text, p.Position))
+	if rg == nil { // NO
+		return l
+	}
+
+	r := completion.NewCompleter(s.Schema).Complete(rg.text, offset)
+	for _, item := range r.Items {
+		ci := &CompletionItem{Label: item.Label, Kind: completionKind(item.Kind), Detail: item.Detail}
+		ci.TextEdit = &TextEdit{Range: toRange(text, rg.toDocument(r.Start), rg.toDocument(r.End)), NewText: item.Insert}
+		l.Items = append(l.Items, ci)
+	}
+	return l
+}
+
+func (s *Server) hover(p positionParams) *Hover {
+	text := []rune(s.documents[p.TextDocument.URI])
+
+	// Cursor in a Filter?
+	rg, offset := regionAt(s.regions(p.TextDocument.URI, text), toOffset(text, p.Position))
+	if rg == nil { // NO
+		return nil
+	}
+
+	// Find Token under Cursor
+	l := lexer.NewLexer(rg.text)
+	for tok := l.NextToken(); tok.Type != token.EOL; tok = l.NextToken() {
+		start, end := l.Span()
+		if offset < start || offset >= end {
+			continue
+		}
+
+		r := toRange(text, rg.toDocument(start), rg.toDocument(end))
+		switch tok.Type {
+		case token.IDENT:
+			// Function Name?
+			if doc, ok := docs[strings.ToUpper(tok.Literal)]; ok && l.NextToken().Type == token.LPAREN { // YES
+				return &Hover{Contents: MarkupContent{Kind: "markdown", Value: fmt.Sprintf("```\n%s\n```\n%s", completion.Signature(tok.Literal), doc)}, Range: &r}
+			}
+			fallthrough
+		case token.FIELD:
+			if s.Schema == nil {
+				return nil
+			}
+
+			if f := s.Schema.Field(tok.Literal); f != nil {
+				return &Hover{Contents: MarkupContent{Kind: "markdown", Value: fieldDoc(f)}, Range: &r}
+			}
+		}
+		return nil
+	}
+
+	return nil
+}
+
+func (s *Server) formatting(p formattingParams) []*TextEdit {
+	edits := make([]*TextEdit, 0)
+	text := []rune(s.documents[p.TextDocument.URI])
+
+	pr := printer.NewPrinter()
+	if p.Options.TabSize > 0 {
+		pr.Indent = strings.Repeat(" ", p.Options.TabSize)
+		if !p.Options.InsertSpaces {
+			pr.Indent = "\t"
+		}
+	}
+
+	for _, rg := range s.regions(p.TextDocument.URI, text) {
+		// Only Format Filters without Parse Errors
+		f, errors := parser.NewParser(lexer.NewLexer(rg.text)).ParseFilterTolerant()
+		if len(errors) > 0 || f.F == nil {
+			continue
+		}
+
+		// Line Breaks only where the Value can Span Lines
+		pr.Width = printer.DEFAULT_WIDTH
+		if rg.style != STYLE_DOCUMENT && rg.style != STYLE_BLOCK {
+			pr.Width = 0
+		}
+
+		formatted := rg.encode(pr.Print(f))
+		if formatted != string(text[rg.start:rg.end]) {
+			edits = append(edits, &TextEdit{Range: toRange(text, rg.start, rg.end), NewText: formatted})
+		}
+	}
+	return edits
+}
+
+func (s *Server) publish(uri string, diagnostics []*Diagnostic) {
+	s.write(&message{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics", Params: mustMarshal(&publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})})
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, e *responseError) {
+	m := &message{JSONRPC: "2.0", ID: id, Error: e}
+	if e == nil {
+		// "result" is Required in Successful Responses (even if null)
+		if result == nil {
+			result = json.RawMessage("null")
+		}
+		m.Result = result
+	}
+	s.write(m)
+}
+
+// Read Next Message (nil - Invalid Message, Error Already Sent)
+func (s *Server) read() (*message, error) {
+	headers, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length [%s]", headers.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+
+	m := &message{}
+	if err := json.Unmarshal(body, m); err != nil {
+		s.log("invalid message: %s", err.Error())
+		s.reply(nil, nil, &responseError{Code: ERR_PARSE, Message: err.Error()})
+		return nil, nil
+	}
+	return m, nil
+}
+
+func (s *Server) write(m *message) {
+	body := mustMarshal(m)
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *Server) log(format string, args ...interface{}) {
+	if s.Log != nil {
+		fmt.Fprintf(s.Log, format+"\n", args...)
+	}
+}
+
+func decode(params json.RawMessage, v interface{}) *responseError {
+	// No Parameters?
+	if len(params) == 0 { // YES: Use Defaults
+		return nil
+	}
+
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: ERR_INVALID_PARAMS, Message: err.Error()}
+	}
+	return nil
+}
+
+func mustMarshal(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func completionKind(kind string) int {
+	switch kind {
+	case completion.KIND_FUNCTION:
+		return COMPLETION_FUNCTION
+	case completion.KIND_FIELD:
+		return COMPLETION_FIELD
+	case completion.KIND_MACRO:
+		return COMPLETION_MODULE
+	case completion.KIND_TYPE:
+		return COMPLETION_TYPE_PARAMETER
+	}
+	return COMPLETION_VALUE
+}
+
+func fieldDoc(f *schema.Field) string {
+	doc := fmt.Sprintf("field `%s`: %s", f.Name, f.Type)
+	if f.Collection {
+		doc = fmt.Sprintf("field `%s`: collection of %s", f.Name, f.Type)
+	}
+
+	if len(f.Values) > 0 {
+		doc += "\n\nvalues: " + strings.Join(f.Values, ", ")
+	}
+	return doc
+}
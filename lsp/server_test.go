@@ -0,0 +1,221 @@
+package lsp
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/objectvault/filter-parser/schema"
+	"github.com/objectvault/filter-parser/token"
+)
+
+// Scripted Session: Requests (id > 0) and Notifications (id 0)
+type step struct {
+	id     int
+	method string
+	params string
+}
+
+func script(steps []step) *bytes.Buffer {
+	var b bytes.Buffer
+	for _, s := range steps {
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","method":%q`, s.method)
+		if s.id > 0 {
+			body += fmt.Sprintf(`,"id":%d`, s.id)
+		}
+		if s.params != "" {
+			body += `,"params":` + s.params
+		}
+		body += "}"
+		fmt.Fprintf(&b, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	return &b
+}
+
+type reply struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+func replies(t *testing.T, out *bytes.Buffer) []*reply {
+	l := make([]*reply, 0)
+	r := bufio.NewReader(out)
+	for {
+		headers, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err != nil {
+			return l
+		}
+
+		length, _ := strconv.Atoi(headers.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			t.Fatalf("reading reply body: %s", err.Error())
+		}
+
+		m := &reply{}
+		if err := json.Unmarshal(body, m); err != nil {
+			t.Fatalf("invalid reply [%s]: %s", body, err.Error())
+		}
+		l = append(l, m)
+	}
+}
+
+func testSchema() *schema.Schema {
+	s := schema.NewSchema()
+	s.AddField("state", token.STRING).SetValues("active", "archived")
+	s.AddField("created", token.INT)
+	return s
+}
+
+func TestSession(t *testing.T) {
+	in := script([]step{
+		{1, "initialize", `{}`},
+		{0, "initialized", `{}`},
+		{0, "textDocument/didOpen", `{"textDocument":{"uri":"file:///a.filter","version":1,"text":"and(eq(state, \"active\"), gt(created 1)"}}`},
+		{0, "textDocument/didChange", `{"textDocument":{"uri":"file:///a.filter"},"contentChanges":[{"text":"and(eq(state,\"active\"),gt(created,1))"}]}`},
+		{2, "textDocument/completion", `{"textDocument":{"uri":"file:///a.filter"},"position":{"line":0,"character":7}}`},
+		{3, "textDocument/hover", `{"textDocument":{"uri":"file:///a.filter"},"position":{"line":0,"character":4}}`},
+		{4, "textDocument/formatting", `{"textDocument":{"uri":"file:///a.filter"},"options":{"tabSize":2,"insertSpaces":true}}`},
+		{5, "unknown/method", `{}`},
+		{6, "shutdown", ""},
+		{0, "exit", ""},
+	})
+
+	var out bytes.Buffer
+	s := NewServer(in, &out)
+	s.Schema = testSchema()
+
+	if code := s.Run(); code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d", code)
+	}
+
+	tests := []struct {
+		expectedID       int    // 0 - Notification
+		expectedMethod   string // Notification Method
+		expectedContains string // Result or Params Substring
+	}{
+		{1, "", `"documentFormattingProvider":true`},
+		{0, "textDocument/publishDiagnostics", `"message":"FUNCTION PARAMS: expecting \",\""`},
+		{0, "textDocument/publishDiagnostics", `"diagnostics":[]`},
+		{2, "", `"label":"state"`},
+		{3, "", `eq(field, value)`},
+		{4, "", `"newText":"and(eq(state, \"active\"), gt(created, 1))"`},
+		{5, "", `"code":-32601`},
+		{6, "", `null`},
+	}
+
+	rs := replies(t, &out)
+	if len(rs) != len(tests) {
+		t.Fatalf("number of replies wrong. expected=%d, got=%d", len(tests), len(rs))
+	}
+
+	for i, tt := range tests {
+		r := rs[i]
+
+		id := 0
+		if r.ID != nil {
+			id = *r.ID
+		}
+
+		if id != tt.expectedID || r.Method != tt.expectedMethod {
+			t.Fatalf("tests[%d] - reply wrong. expected=[%d %q], got=[%d %q]",
+				i, tt.expectedID, tt.expectedMethod, id, r.Method)
+		}
+
+		body := string(r.Result) + string(r.Params)
+		if r.Error != nil {
+			body += fmt.Sprintf(`"code":%d`, r.Error.Code)
+		}
+
+		if !strings.Contains(body, tt.expectedContains) {
+			t.Fatalf("tests[%d] - content wrong. expected=%q, got=%q",
+				i, tt.expectedContains, body)
+		}
+	}
+}
+
+func TestNotInitialized(t *testing.T) {
+	in := script([]step{
+		{1, "textDocument/hover", `{"textDocument":{"uri":"file:///a.filter"},"position":{"line":0,"character":0}}`},
+		{0, "exit", ""},
+	})
+
+	var out bytes.Buffer
+	if code := NewServer(in, &out).Run(); code != 1 {
+		t.Fatalf("exit code wrong. expected=1, got=%d", code)
+	}
+
+	rs := replies(t, &out)
+	if len(rs) != 1 || rs[0].Error == nil || rs[0].Error.Code != ERR_NOT_INITIALIZED {
+		t.Fatalf("reply wrong. expected error [%d], got=%v", ERR_NOT_INITIALIZED, rs)
+	}
+}
+
+func TestInvalidMessage(t *testing.T) {
+	body := `{"jsonrpc":"2.0",`
+	in := bytes.NewBufferString(fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body))
+	in.Write(script([]step{{0, "exit", ""}}).Bytes())
+
+	var out bytes.Buffer
+	if code := NewServer(in, &out).Run(); code != 1 {
+		t.Fatalf("exit code wrong. expected=1, got=%d", code)
+	}
+
+	// Only the Parse Error is Sent (the Invalid Message is not Handled)
+	rs := replies(t, &out)
+	if len(rs) != 1 || rs[0].Error == nil || rs[0].Error.Code != ERR_PARSE {
+		t.Fatalf("reply wrong. expected error [%d], got=%v", ERR_PARSE, rs)
+	}
+}
+
+func TestDiagnosticRanges(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+		start   int
+		end     int
+	}{
+		{`eq(zz, 1)`, "Function [EQ] Field [zz] is not recognized", 0, 9},
+		{`and(eq(state, "a"), gt(created, "x"))`, "Function [GT] Field [created] expects a value of type [INT] not [STRING]", 20, 36},
+		{"or(eq(state, \"a\"),\n  lt(state, 1))", "Function [LT] Field [state] expects a value of type [STRING] not [INT]", 2, 14},
+		{`and(eq(state, "a"), gt(created 1))`, `FUNCTION PARAMS: expecting ","`, 31, 31},
+	}
+
+	s := NewServer(nil, nil)
+	s.Schema = testSchema()
+	for i, tt := range tests {
+		d := s.diagnostics("file:///a.filter", tt.input)
+		if len(d) != 1 {
+			t.Fatalf("tests[%d] - diagnostics wrong. expected=1, got=%d", i, len(d))
+		}
+
+		if d[0].Message != tt.message {
+			t.Fatalf("tests[%d] - message wrong. expected=%q, got=%q", i, tt.message, d[0].Message)
+		}
+
+		// Characters are Relative to the Line
+		if d[0].Range.Start.Character != tt.start || d[0].Range.End.Character != tt.end {
+			t.Fatalf("tests[%d] - range wrong. expected=[%d, %d), got=[%d, %d)",
+				i, tt.start, tt.end, d[0].Range.Start.Character, d[0].Range.End.Character)
+		}
+	}
+}
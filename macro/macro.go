@@ -0,0 +1,203 @@
+package macro
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/binder"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  MACROS
+  - Registered fragments are referenced as macro(name) or, for
+    parameterized fragments, macro(name, value, ...)
+  - Arguments are bound to the fragment's parameters by position, both as
+    $1, $2, ... and by the declared parameter names
+  - Macros can reference other macros, expansion stops on cycles or when
+    MaxDepth is exceeded
+*/
+
+// Default Maximum Macro Nesting
+const DEFAULT_MAX_DEPTH = 8
+
+// Macro Error Object
+type MacroError struct {
+	Message string
+}
+
+func (e *MacroError) ToString() string {
+	return e.Message
+}
+
+// Named Filter Fragment
+type Macro struct {
+	Name   string
+	Filter *ast.Filter
+	Params []string // Parameter Names (in Argument Order)
+}
+
+// Registry Object
+type Registry struct {
+	macros   map[string]*Macro
+	MaxDepth int
+}
+
+func NewRegistry() *Registry {
+	r := &Registry{macros: make(map[string]*Macro), MaxDepth: DEFAULT_MAX_DEPTH}
+	return r
+}
+
+func (r *Registry) Register(name string, f *ast.Filter, params ...string) (*Macro, *MacroError) {
+	// Macro Names are Case Insensitive
+	name = strings.ToLower(name)
+
+	// Have a Fragment?
+	if f == nil || f.F == nil { // NO: Nothing to Expand to
+		return nil, &MacroError{Message: fmt.Sprintf("Macro [%s] has no filter", name)}
+	}
+
+	m := &Macro{Name: name, Filter: f, Params: params}
+	r.macros[name] = m
+	return m, nil
+}
+
+// Parse and Register Fragment
+func (r *Registry) RegisterText(name string, filter string, params ...string) (*Macro, *MacroError) {
+	rp := parser.NewParser(lexer.NewLexer(filter)).ParseFilter()
+
+	f, ok := rp.(*ast.Filter)
+	if !ok {
+		return nil, &MacroError{Message: fmt.Sprintf("Macro [%s] %s", name, rp.(ast.Node).ToString())}
+	}
+
+	return r.Register(name, f, params...)
+}
+
+func (r *Registry) Macro(name string) *Macro {
+	m, ok := r.macros[strings.ToLower(name)]
+	if !ok {
+		return nil
+	}
+	return m
+}
+
+// Registered Macro Names (Sorted)
+func (r *Registry) Names() []string {
+	l := make([]string, 0, len(r.macros))
+	for name := range r.macros {
+		l = append(l, name)
+	}
+
+	sort.Strings(l)
+	return l
+}
+
+// Expanded Copy of Filter
+func (r *Registry) Expand(f *ast.Filter) (*ast.Filter, *MacroError) {
+	if f == nil || f.F == nil {
+		return f, nil
+	}
+
+	ef, e := r.expandFunction(f.F, make([]string, 0))
+	if e != nil {
+		return nil, e
+	}
+
+	return &ast.Filter{F: ef}, nil
+}
+
+func (r *Registry) expandFunction(f *ast.Function, stack []string) (*ast.Function, *MacroError) {
+	// Is Macro Reference?
+	if strings.ToUpper(f.Name.Literal) == "MACRO" { // YES: Replace with Fragment
+		return r.expandMacro(f, stack)
+	}
+
+	ef := &ast.Function{Name: f.Name, Parameters: make([]interface{}, 0, len(f.Parameters))}
+	for _, pi := range f.Parameters {
+		// Is Function?
+		pf, ok := pi.(*ast.Function)
+		if !ok { // NO: Keep Parameter
+			ef.Parameters = append(ef.Parameters, pi)
+			continue
+		}
+
+		ep, e := r.expandFunction(pf, stack)
+		if e != nil {
+			return nil, e
+		}
+		ef.Parameters = append(ef.Parameters, ep)
+	}
+
+	return ef, nil
+}
+
+func (r *Registry) expandMacro(f *ast.Function, stack []string) (*ast.Function, *MacroError) {
+	// 1st Parameter is the Macro Name
+	if len(f.Parameters) == 0 {
+		return nil, &MacroError{Message: "Macro requires a name"}
+	}
+
+	pn, ok := f.Parameters[0].(*ast.Value)
+	if !ok || pn.V.Type != token.IDENT {
+		return nil, &MacroError{Message: "Macro name should be an Identifier"}
+	}
+
+	name := strings.ToLower(pn.V.Literal)
+	m := r.Macro(name)
+	if m == nil {
+		return nil, &MacroError{Message: fmt.Sprintf("Macro [%s] is not registered", name)}
+	}
+
+	// Expanding Itself?
+	for _, s := range stack {
+		if s == name { // YES: Cycle
+			return nil, &MacroError{Message: fmt.Sprintf("Macro [%s] is recursive [%s -> %s]", name, strings.Join(stack, " -> "), name)}
+		}
+	}
+
+	if r.MaxDepth > 0 && len(stack) >= r.MaxDepth {
+		return nil, &MacroError{Message: fmt.Sprintf("Macro [%s] exceeds maximum depth [%d]", name, r.MaxDepth)}
+	}
+
+	// Bind Arguments
+	args := f.Parameters[1:]
+	if len(args) != len(m.Params) {
+		return nil, &MacroError{Message: fmt.Sprintf("Macro [%s] expects [%d] arguments, found [%d]", name, len(m.Params), len(args))}
+	}
+
+	values := make(map[string]interface{})
+	for i, ai := range args {
+		av, ok := ai.(*ast.Value)
+		if !ok || av.V.Type == token.IDENT {
+			return nil, &MacroError{Message: fmt.Sprintf("Macro [%s] argument %d should be a Value", name, i+1)}
+		}
+
+		values[strconv.Itoa(i+1)] = av
+		values[m.Params[i]] = av
+	}
+
+	// NOTE: Parameters that are not Macro Arguments are left for the Caller to Bind
+	bf, be := binder.BindPartial(m.Filter, values)
+	if be != nil {
+		return nil, &MacroError{Message: fmt.Sprintf("Macro [%s] %s", name, be.Message)}
+	}
+
+	// Expand Macros inside the Fragment
+	return r.expandFunction(bf.F, append(stack, name))
+}
@@ -0,0 +1,169 @@
+package macro
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+)
+
+// Parse Input (Fails Test if Input is not a Valid Filter)
+func parseFilter(t *testing.T, input string) *ast.Filter {
+	r := parser.NewParser(lexer.NewLexer(input)).ParseFilter()
+
+	f, ok := r.(*ast.Filter)
+	if !ok {
+		t.Fatalf("parse [%s] failed. got=%T (%+v)", input, r, r)
+	}
+	return f
+}
+
+// Registry with Macros (name, filter and parameter names)
+func testRegistry(t *testing.T, macros ...[]string) *Registry {
+	r := NewRegistry()
+	for _, m := range macros {
+		if _, e := r.RegisterText(m[0], m[1], m[2:]...); e != nil {
+			t.Fatalf("register [%s] failed. got=%q", m[0], e.Message)
+		}
+	}
+	return r
+}
+
+func TestExpand(t *testing.T) {
+	r := testRegistry(t,
+		[]string{"active", `eq(status, "active")`},
+		[]string{"Owned", `eq(owner, $user)`, "user"},
+		[]string{"range", `between(n, $low, $high)`, "low", "high"},
+		[]string{"mine", `and(macro(active), macro(owned, $1))`, "user"},
+		[]string{"open", `and(macro(active), eq(tenant, $tenant))`},
+	)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`macro(active)`, "eq ( status, \"active\" )"},
+		{`not(macro(ACTIVE))`, "not ( eq ( status, \"active\" ) )"},
+		{`macro(owned, 7)`, "eq ( owner, 7 )"},
+		{`macro(owned, @creator)`, "eq ( owner, @creator )"},
+		{`macro(range, 1, 10)`, "between ( n, 1, 10 )"},
+		{`macro(mine, "bob")`, "and ( eq ( status, \"active\" ), eq ( owner, \"bob\" ) )"},
+		{`or(macro(owned, $me), eq(a, 1))`, "or ( eq ( owner, $me ), eq ( a, 1 ) )"},
+		{`macro(open)`, "and ( eq ( status, \"active\" ), eq ( tenant, $tenant ) )"},
+		{`eq(a, 1)`, "eq ( a, 1 )"},
+	}
+
+	for i, tt := range tests {
+		f := parseFilter(t, tt.input)
+
+		ef, e := r.Expand(f)
+		if e != nil {
+			t.Fatalf("tests[%d] - Expand failed. got=%q", i, e.Message)
+		}
+
+		if got := ef.ToString(); got != tt.expected {
+			t.Fatalf("tests[%d] - filter wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+
+		// Input is not Modified
+		if got := f.ToString(); got != parseFilter(t, tt.input).ToString() {
+			t.Fatalf("tests[%d] - input modified. got=%q", i, got)
+		}
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	r := testRegistry(t,
+		[]string{"owned", `eq(owner, $user)`, "user"},
+		[]string{"a", `macro(b)`},
+		[]string{"b", `and(eq(x, 1), macro(a))`},
+		[]string{"self", `not(macro(self))`},
+	)
+
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`macro(zz)`, "Macro [zz] is not registered"},
+		{`macro(owned)`, "Macro [owned] expects [1] arguments, found [0]"},
+		{`macro(owned, 1, 2)`, "Macro [owned] expects [1] arguments, found [2]"},
+		{`macro(owned, other)`, "Macro [owned] argument 1 should be a Value"},
+		{`macro(a)`, "Macro [a] is recursive [a -> b -> a]"},
+		{`and(eq(x, 1), macro(b))`, "Macro [b] is recursive [b -> a -> b]"},
+		{`macro(self)`, "Macro [self] is recursive [self -> self]"},
+	}
+
+	for i, tt := range tests {
+		_, e := r.Expand(parseFilter(t, tt.input))
+		if e == nil || e.Message != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%+v", i, tt.expectedError, e)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	r := testRegistry(t,
+		[]string{"ma", `eq(a, 1)`},
+		[]string{"mb", `not(macro(ma))`},
+		[]string{"mc", `not(macro(mb))`},
+	)
+
+	tests := []struct {
+		maxDepth      int
+		expectedError string
+	}{
+		{0, ""},
+		{3, ""},
+		{2, "Macro [ma] exceeds maximum depth [2]"},
+		{1, "Macro [mb] exceeds maximum depth [1]"},
+	}
+
+	for i, tt := range tests {
+		r.MaxDepth = tt.maxDepth
+
+		got := ""
+		if _, e := r.Expand(parseFilter(t, `macro(mc)`)); e != nil {
+			got = e.Message
+		}
+
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRegistry()
+
+	if _, e := r.Register("Empty", nil); e == nil || e.Message != "Macro [empty] has no filter" {
+		t.Fatalf("nil filter - error wrong. got=%+v", e)
+	}
+
+	if _, e := r.Register("empty", &ast.Filter{}); e == nil || e.Message != "Macro [empty] has no filter" {
+		t.Fatalf("nil function - error wrong. got=%+v", e)
+	}
+
+	if _, e := r.RegisterText("bad", `eq(a, 1`); e == nil {
+		t.Fatalf("invalid filter - expected error")
+	}
+
+	if r.Macro("empty") != nil || r.Macro("bad") != nil {
+		t.Fatalf("invalid macros were registered")
+	}
+
+	m, e := r.Register("Active", parseFilter(t, `eq(status, "active")`))
+	if e != nil || r.Macro("ACTIVE") != m || m.Name != "active" {
+		t.Fatalf("register failed. got=%+v, %+v", m, e)
+	}
+}
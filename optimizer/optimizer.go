@@ -0,0 +1,214 @@
+package optimizer
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/builder"
+	"github.com/objectvault/filter-parser/cost"
+	"github.com/objectvault/filter-parser/limits"
+	"github.com/objectvault/filter-parser/schema"
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  OPTIMIZATION PASSES
+  - Reorder: and(a, and(b, c)) terms are sorted by estimated cost and
+    selectivity, so the cheapest, most selective predicate comes first
+  - Merge IN: or(eq(f, 1), eq(f, 2), in(f, 3)) over an indexed field
+    becomes in(f, 1, 2, 3)
+
+  Rewritten chains are rebuilt right nested: and(t1, and(t2, t3))
+
+  ASSUMPTION: Filter has been run through the Syntax Checker, and the
+  original filter is not modified (the optimized filter is a copy)
+*/
+
+// Optimizer Object
+type Optimizer struct {
+	Model   *cost.Model    // Cost Model (Field Index Metadata)
+	Reorder bool           // Reorder AND Terms
+	MergeIN bool           // Rewrite OR of EQ over an Indexed Field to IN
+	Limits  *limits.Limits // OPTIONAL: Resource Limits (IN is kept within MaxListSize)
+}
+
+func NewOptimizer(s *schema.Schema) *Optimizer {
+	o := &Optimizer{Model: cost.NewModel(s), Reorder: true, MergeIN: true}
+	return o
+}
+
+func (o *Optimizer) Optimize(f *ast.Filter) *ast.Filter {
+	// Have Conditions?
+	if f == nil || f.F == nil { // NO: Nothing to Optimize
+		return f
+	}
+
+	return builder.ASTFilter(o.function(f.F.Clone()))
+}
+
+func (o *Optimizer) function(f *ast.Function) *ast.Function {
+	switch strings.ToUpper(f.Name.Literal) {
+	case "AND":
+		terms := o.terms("AND", f, nil)
+		if o.Reorder {
+			terms = o.reorder(terms)
+		}
+		return rebuild("AND", terms)
+	case "OR":
+		terms := o.terms("OR", f, nil)
+		if o.MergeIN {
+			terms = o.mergeIN(terms)
+		}
+		return rebuild("OR", terms)
+	case "NOT":
+		f.Parameters[0] = o.function(f.Parameters[0].(*ast.Function))
+	}
+
+	return f
+}
+
+// Flatten Chain of Logical Operator into (Optimized) Terms
+func (o *Optimizer) terms(name string, f *ast.Function, terms []*ast.Function) []*ast.Function {
+	for _, pi := range f.Parameters {
+		pf := pi.(*ast.Function)
+
+		// Same Logical Operator?
+		if strings.ToUpper(pf.Name.Literal) == name { // YES: Flatten
+			terms = o.terms(name, pf, terms)
+			continue
+		}
+
+		terms = append(terms, o.function(pf))
+	}
+
+	return terms
+}
+
+// Sort Terms by Cost, then Selectivity (Stable - Ties Keep Original Order)
+func (o *Optimizer) reorder(terms []*ast.Function) []*ast.Function {
+	type scored struct {
+		f           *ast.Function
+		cost        float64
+		selectivity float64
+	}
+
+	s := make([]scored, len(terms))
+	for i, t := range terms {
+		e := o.Model.Estimate(builder.ASTFilter(t))
+		s[i] = scored{f: t, cost: e.Cost, selectivity: e.Selectivity}
+	}
+
+	sort.SliceStable(s, func(i, j int) bool {
+		if s[i].cost != s[j].cost {
+			return s[i].cost < s[j].cost
+		}
+		return s[i].selectivity < s[j].selectivity
+	})
+
+	for i := range s {
+		terms[i] = s[i].f
+	}
+	return terms
+}
+
+// Merge EQ and IN Terms over the Same Indexed Field into a Single IN
+func (o *Optimizer) mergeIN(terms []*ast.Function) []*ast.Function {
+	// Group Mergeable Terms by Field
+	groups := make(map[string][]int)
+	for i, t := range terms {
+		if field := o.mergeable(t); field != "" {
+			groups[field] = append(groups[field], i)
+		}
+	}
+
+	merged := make([]*ast.Function, 0, len(terms))
+	skip := make(map[int]bool)
+	for i, t := range terms {
+		if skip[i] {
+			continue
+		}
+
+		// Term Shares its Field with Others?
+		field := o.mergeable(t)
+		if field == "" || len(groups[field]) < 2 { // NO: Keep Term
+			merged = append(merged, t)
+			continue
+		}
+
+		// Collect Values (Without Duplicates)
+		values := make([]*ast.Value, 0)
+		seen := make(map[token.Token]bool)
+		for _, j := range groups[field] {
+			skip[j] = true
+			for _, pi := range terms[j].Parameters[1:] {
+				v := pi.(*ast.Value)
+				if !seen[v.V] {
+					seen[v.V] = true
+					values = append(values, v)
+				}
+			}
+		}
+
+		// Would IN be too Large?
+		if o.Limits != nil && limits.Exceeds(len(values), o.Limits.MaxListSize) { // YES: Keep Terms
+			for _, j := range groups[field] {
+				merged = append(merged, terms[j])
+			}
+			continue
+		}
+
+		merged = append(merged, builder.ASTINLIST(field, values...))
+	}
+
+	return merged
+}
+
+// Field of EQ / IN Term that can be Merged ("" - Not Mergeable)
+func (o *Optimizer) mergeable(f *ast.Function) string {
+	fname := strings.ToUpper(f.Name.Literal)
+	if fname != "EQ" && fname != "IN" {
+		return ""
+	}
+
+	// Values have to be Literals or Parameters (not Field References)
+	for _, pi := range f.Parameters[1:] {
+		if v := pi.(*ast.Value); v.V.Type == token.FIELD || v.V.Type == token.IDENT {
+			return ""
+		}
+	}
+
+	// Is the Field Indexed?
+	field := strings.ToLower(f.Parameters[0].(*ast.Value).V.Literal)
+	if o.Model.Schema == nil {
+		return ""
+	}
+
+	if sf := o.Model.Schema.Field(field); sf == nil || !sf.Indexed || sf.Collection { // NO
+		return ""
+	}
+	return field
+}
+
+// Rebuild Right Nested Chain: op(t1, op(t2, t3))
+func rebuild(name string, terms []*ast.Function) *ast.Function {
+	f := terms[len(terms)-1]
+	for i := len(terms) - 2; i >= 0; i-- {
+		if name == "AND" {
+			f = builder.ASTAND(terms[i], f)
+		} else {
+			f = builder.ASTOR(terms[i], f)
+		}
+	}
+	return f
+}
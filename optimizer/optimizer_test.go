@@ -0,0 +1,145 @@
+package optimizer
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/limits"
+	"github.com/objectvault/filter-parser/parser"
+	"github.com/objectvault/filter-parser/schema"
+	"github.com/objectvault/filter-parser/token"
+)
+
+// Schema with Indexed (id, state, tags) and Unindexed (name) Fields
+func testSchema() *schema.Schema {
+	s := schema.NewSchema()
+	s.AddField("id", token.INT).SetIndex(true, 1000)
+	s.AddField("state", token.STRING).SetIndex(true, 0).Selectivity = 0.5
+	s.AddField("name", token.STRING).SetIndex(false, 100)
+	s.AddCollection("tags", token.STRING).SetIndex(true, 0)
+	return s
+}
+
+// Parse Input (Fails Test if Input is not a Valid Filter)
+func parseFilter(t *testing.T, input string) *ast.Filter {
+	r := parser.NewParser(lexer.NewLexer(input)).ParseFilter()
+
+	f, ok := r.(*ast.Filter)
+	if !ok {
+		t.Fatalf("parse [%s] failed. got=%T (%+v)", input, r, r)
+	}
+	return f
+}
+
+func TestReorder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`and(eq(id, 1), eq(name, "x"))`, `AND ( eq ( id, 1 ), eq ( name, "x" ) )`},
+		{`and(eq(name, "x"), eq(id, 1))`, `AND ( eq ( id, 1 ), eq ( name, "x" ) )`},
+		{`and(eq(name, "x"), and(eq(state, "a"), eq(id, 1)))`, `AND ( eq ( id, 1 ), AND ( eq ( state, "a" ), eq ( name, "x" ) ) )`},
+		{`and(and(eq(name, "x"), gt(id, 5)), eq(state, "a"))`, `AND ( gt ( id, 5 ), AND ( eq ( state, "a" ), eq ( name, "x" ) ) )`},
+		// Same Cost: More Selective First, Ties Keep their Order
+		{`and(neq(name, "y"), eq(name, "x"))`, `AND ( eq ( name, "x" ), neq ( name, "y" ) )`},
+		{`and(endswith(name, "x"), contains(name, "y"))`, `AND ( endswith ( name, "x" ), contains ( name, "y" ) )`},
+		{`not(and(eq(name, "x"), eq(id, 1)))`, `not ( AND ( eq ( id, 1 ), eq ( name, "x" ) ) )`},
+		{`eq(name, "x")`, `eq ( name, "x" )`},
+	}
+
+	o := NewOptimizer(testSchema())
+	for i, tt := range tests {
+		got := o.Optimize(parseFilter(t, tt.input)).ToString()
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - filter wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestMergeIN(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`or(eq(id, 1), eq(id, 2))`, `IN ( id, 1, 2 )`},
+		{`or(eq(id, 1), or(in(id, 2, 3), eq(id, 1)))`, `IN ( id, 1, 2, 3 )`},
+		{`or(eq(id, $1), eq(id, $2))`, `IN ( id, $1, $2 )`},
+		{`or(eq(id, 1), or(eq(name, "x"), eq(id, 2)))`, `OR ( IN ( id, 1, 2 ), eq ( name, "x" ) )`},
+		{`or(eq(id, 1), eq(state, "a"))`, `OR ( eq ( id, 1 ), eq ( state, "a" ) )`},
+		// Unindexed Fields, Collections and Field References are Kept
+		{`or(eq(name, "x"), eq(name, "y"))`, `OR ( eq ( name, "x" ), eq ( name, "y" ) )`},
+		{`or(has(tags, "x"), has(tags, "y"))`, `OR ( has ( tags, "x" ), has ( tags, "y" ) )`},
+		{`or(eq(id, @id), eq(id, 2))`, `OR ( eq ( id, @id ), eq ( id, 2 ) )`},
+		{`and(eq(name, "x"), or(eq(id, 1), eq(id, 2)))`, `AND ( IN ( id, 1, 2 ), eq ( name, "x" ) )`},
+	}
+
+	o := NewOptimizer(testSchema())
+	for i, tt := range tests {
+		got := o.Optimize(parseFilter(t, tt.input)).ToString()
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - filter wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		input    string
+		reorder  bool
+		mergeIN  bool
+		limits   *limits.Limits
+		expected string
+	}{
+		{`and(eq(name, "x"), eq(id, 1))`, false, true, nil, `AND ( eq ( name, "x" ), eq ( id, 1 ) )`},
+		{`or(eq(id, 1), eq(id, 2))`, true, false, nil, `OR ( eq ( id, 1 ), eq ( id, 2 ) )`},
+		{`or(eq(id, 1), or(eq(id, 2), eq(id, 3)))`, true, true, &limits.Limits{MaxListSize: 3}, `IN ( id, 1, 2, 3 )`},
+		{`or(eq(id, 1), or(eq(id, 2), eq(id, 3)))`, true, true, &limits.Limits{MaxListSize: 2}, `OR ( eq ( id, 1 ), OR ( eq ( id, 2 ), eq ( id, 3 ) ) )`},
+	}
+
+	for i, tt := range tests {
+		o := NewOptimizer(testSchema())
+		o.Reorder = tt.reorder
+		o.MergeIN = tt.mergeIN
+		o.Limits = tt.limits
+
+		got := o.Optimize(parseFilter(t, tt.input)).ToString()
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - filter wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestOptimizeCopies(t *testing.T) {
+	input := `and(eq(name, "x"), or(eq(id, 1), eq(id, 2)))`
+	f := parseFilter(t, input)
+	before := f.ToString()
+
+	o := NewOptimizer(testSchema())
+	if got := o.Optimize(f).ToString(); got == before {
+		t.Fatalf("filter not optimized. got=%q", got)
+	}
+	if f.ToString() != before {
+		t.Fatalf("original filter modified. expected=%q, got=%q", before, f.ToString())
+	}
+
+	// Without Schema: Nothing is Indexed, Order is Kept
+	if got := NewOptimizer(nil).Optimize(parseFilter(t, input)).ToString(); got != `AND ( eq ( name, "x" ), OR ( eq ( id, 1 ), eq ( id, 2 ) ) )` {
+		t.Fatalf("filter without schema wrong. got=%q", got)
+	}
+
+	// Empty Filter
+	if o.Optimize(nil) != nil {
+		t.Fatalf("nil filter optimized")
+	}
+}
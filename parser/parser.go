@@ -15,6 +15,7 @@ import (
 
 	"github.com/objectvault/filter-parser/ast"
 	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/limits"
 	"github.com/objectvault/filter-parser/token"
 )
 
@@ -23,6 +24,11 @@ type Parser struct {
 	l         *lexer.Lexer
 	curToken  token.Token
 	peekToken token.Token
+	Limits    *limits.Limits // Resource Limits (nil - No Limits)
+	depth     int            // Current Function Nesting
+	nodes     int            // Functions and Values Parsed
+	curSpan   [2]int         // Position of Current Token [start, end)
+	peekSpan  [2]int         // Position of Peek Token [start, end)
 }
 
 func NewParser(l *lexer.Lexer) *Parser {
@@ -34,6 +40,11 @@ func NewParser(l *lexer.Lexer) *Parser {
 }
 
 func (p *Parser) ParseFilter() interface{} {
+	// Input too Long?
+	if e := p.checkInputLength(); e != nil { // YES: Don't Parse
+		return e
+	}
+
 	// Is 1st Token an Identifier?
 	if p.curToken.Type == token.IDENT { // YES
 		// Looks like the start of a function
@@ -62,6 +73,15 @@ func (p *Parser) ParseFilter() interface{} {
 }
 
 func (p *Parser) parseFunction(name token.Token) interface{} {
+	// Track Nesting
+	p.depth++
+	defer func() { p.depth-- }()
+
+	// Nested too Deep or too Many Nodes?
+	if e := p.checkNode(); e != nil { // YES: Stop Parsing
+		return e
+	}
+
 	// Expecting "("
 	if p.curToken.Type != token.LPAREN { // NOT FOUND
 		return &ast.ParseError{Message: "FUNCTION: expecting function \"(\""}
@@ -114,16 +134,22 @@ func (p *Parser) parseFunctionParameters() interface{} {
 
 			// Parsed Function without Errors?
 			if _, ok := n.(*ast.ParseError); ok { // NO: Stop Parsing
-				finished = true
+				return n
 			} else                              // Any More Parameters?
 			if p.curToken.Type == token.COMMA { // YES: Position at Start of Next Parameter
 				p.nextToken()
 			}
 		case token.COMMA:
 			n = &ast.Value{V: current}
+			if e := p.checkValue(n.(*ast.Value)); e != nil { // Value over Limits: Stop Parsing
+				return e
+			}
 			p.nextToken() // Consume ','
 		case token.RPAREN:
 			n = &ast.Value{V: current}
+			if e := p.checkValue(n.(*ast.Value)); e != nil { // Value over Limits: Stop Parsing
+				return e
+			}
 			finished = true
 		default:
 			return &ast.ParseError{Message: fmt.Sprintf("FUNCTION PARAMS: unexpected token type [%q]\n", p.peekToken.Type)}
@@ -140,9 +166,55 @@ func (p *Parser) parseFunctionParameters() interface{} {
 	return params
 }
 
+func (p *Parser) checkInputLength() *ast.ParseError {
+	// Smallest of the Lexer and Parser Limits
+	max := p.l.MaxLength
+	if p.Limits != nil && p.Limits.MaxInputLength > 0 && (max <= 0 || p.Limits.MaxInputLength < max) {
+		max = p.Limits.MaxInputLength
+	}
+
+	if limits.Exceeds(p.l.Length(), max) {
+		return &ast.ParseError{Code: limits.ERR_INPUT_LENGTH, Message: fmt.Sprintf("FILTER: input exceeds maximum length [%d]", max)}
+	}
+	return nil
+}
+
+func (p *Parser) checkNode() *ast.ParseError {
+	p.nodes++
+
+	// NOTE: Nesting is Always Bounded (Recursive Descent)
+	if max := limits.MaxDepth(p.Limits); limits.Exceeds(p.depth, max) {
+		return &ast.ParseError{Code: limits.ERR_DEPTH, Message: fmt.Sprintf("FUNCTION: exceeds maximum nesting [%d]", max)}
+	}
+
+	if p.Limits == nil {
+		return nil
+	}
+
+	if limits.Exceeds(p.nodes, p.Limits.MaxNodes) {
+		return &ast.ParseError{Code: limits.ERR_NODES, Message: fmt.Sprintf("FILTER: exceeds maximum number of nodes [%d]", p.Limits.MaxNodes)}
+	}
+
+	return nil
+}
+
+func (p *Parser) checkValue(v *ast.Value) *ast.ParseError {
+	if e := p.checkNode(); e != nil {
+		return e
+	}
+
+	if p.Limits != nil && v.V.Type == token.STRING && limits.Exceeds(len([]rune(v.V.Literal)), p.Limits.MaxStringLength) {
+		return &ast.ParseError{Code: limits.ERR_STRING_LENGTH, Message: fmt.Sprintf("FUNCTION PARAMS: string exceeds maximum length [%d]", p.Limits.MaxStringLength)}
+	}
+
+	return nil
+}
+
 func (p *Parser) nextToken() token.Token {
 	current := p.curToken
 	p.curToken = p.peekToken
+	p.curSpan = p.peekSpan
 	p.peekToken = p.l.NextToken()
+	p.peekSpan[0], p.peekSpan[1] = p.l.Span()
 	return current
 }
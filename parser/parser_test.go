@@ -0,0 +1,130 @@
+package parser
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/limits"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input         string
+		limits        *limits.Limits
+		expectedCode  string
+		expectedError string
+	}{
+		{`not(not(eq(a, "abcd")))`, nil, "", ""},
+		{`eq(a, 1)`, &limits.Limits{MaxInputLength: 8}, "", ""},
+		{`eq(a, 10)`, &limits.Limits{MaxInputLength: 8}, limits.ERR_INPUT_LENGTH, "FILTER: input exceeds maximum length [8]"},
+		{`not(not(eq(a, 1)))`, &limits.Limits{MaxDepth: 3}, "", ""},
+		{`not(not(eq(a, 1)))`, &limits.Limits{MaxDepth: 2}, limits.ERR_DEPTH, "FUNCTION: exceeds maximum nesting [2]"},
+		{`and(eq(a, 1), eq(b, 2))`, &limits.Limits{MaxNodes: 7}, "", ""},
+		{`and(eq(a, 1), eq(b, 2))`, &limits.Limits{MaxNodes: 6}, limits.ERR_NODES, "FILTER: exceeds maximum number of nodes [6]"},
+		{`eq(a, "abc")`, &limits.Limits{MaxStringLength: 3}, "", ""},
+		{`eq(a, "abcd")`, &limits.Limits{MaxStringLength: 3}, limits.ERR_STRING_LENGTH, "FUNCTION PARAMS: string exceeds maximum length [3]"},
+		{`in(a, "abcd", "x")`, &limits.Limits{MaxStringLength: 3}, limits.ERR_STRING_LENGTH, "FUNCTION PARAMS: string exceeds maximum length [3]"},
+	}
+
+	for i, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		p.Limits = tt.limits
+
+		code, got := "", ""
+		if e, ok := p.ParseFilter().(*ast.ParseError); ok {
+			code, got = e.Code, e.Message
+		}
+
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+
+		if code != tt.expectedCode {
+			t.Fatalf("tests[%d] - code wrong. expected=%q, got=%q", i, tt.expectedCode, code)
+		}
+	}
+
+	// Limits are Opt-In
+	if p := NewParser(lexer.NewLexer("")); p.Limits != nil {
+		t.Fatalf("default limits wrong. expected=nil, got=%+v", p.Limits)
+	}
+}
+
+// Nesting is Bounded even without Limits
+func TestStackDepth(t *testing.T) {
+	nested := func(depth int) string {
+		return strings.Repeat("not(", depth-1) + "eq(a, 1)" + strings.Repeat(")", depth-1)
+	}
+
+	tests := []struct {
+		input        string
+		limits       *limits.Limits
+		expectedCode string
+	}{
+		{nested(limits.STACK_MAX_DEPTH), nil, ""},
+		{nested(limits.STACK_MAX_DEPTH + 1), nil, limits.ERR_DEPTH},
+		{nested(limits.STACK_MAX_DEPTH + 1), &limits.Limits{}, limits.ERR_DEPTH},
+		{nested(limits.STACK_MAX_DEPTH + 1), &limits.Limits{MaxDepth: limits.STACK_MAX_DEPTH + 1}, ""},
+	}
+
+	for i, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		p.Limits = tt.limits
+
+		code := ""
+		if e, ok := p.ParseFilter().(*ast.ParseError); ok {
+			code = e.Code
+		}
+
+		if code != tt.expectedCode {
+			t.Fatalf("tests[%d] - code wrong. expected=%q, got=%q", i, tt.expectedCode, code)
+		}
+	}
+}
+
+func TestLexerLimit(t *testing.T) {
+	tests := []struct {
+		input          string
+		lexerLimit     int
+		parserLimit    int
+		expectedResult string
+	}{
+		{`eq(a, 10)`, 9, 0, "eq ( a, 10 )"},
+		{`eq(a, 10)`, 8, 0, "FILTER: input exceeds maximum length [8]"},
+		{`eq(a, 10)`, 8, 4, "FILTER: input exceeds maximum length [4]"},
+		{`eq(a, 10)`, 4, 8, "FILTER: input exceeds maximum length [4]"},
+	}
+
+	for i, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		l.MaxLength = tt.lexerLimit
+
+		p := NewParser(l)
+		if tt.parserLimit > 0 {
+			p.Limits = &limits.Limits{MaxInputLength: tt.parserLimit}
+		}
+
+		r := p.ParseFilter()
+
+		got := r.(ast.Node).ToString()
+		if e, ok := r.(*ast.ParseError); ok {
+			got = e.Message
+		}
+
+		if got != tt.expectedResult {
+			t.Fatalf("tests[%d] - result wrong. expected=%q, got=%q", i, tt.expectedResult, got)
+		}
+	}
+}
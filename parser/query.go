@@ -0,0 +1,326 @@
+package parser
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  Query ::= Clause | Clause Query
+  Clause ::= Fields | Function | Group | Having | Sort | Limit
+  Fields ::= "fields" "(" FieldList ")"
+  FieldList ::= Field | Field "," FieldList
+  Field ::= <IDENTIFIER> | Aggregate
+  Aggregate ::= <IDENTIFIER> "(" ")" | <IDENTIFIER> "(" <IDENTIFIER> ")"
+  Group ::= "group" "(" IdentifierList ")"
+  IdentifierList ::= <IDENTIFIER> | <IDENTIFIER> "," IdentifierList
+  Having ::= "having" "(" Function ")"
+  Sort ::= "sort" "(" SortList ")"
+  SortList ::= SortField | SortField "," SortList
+  SortField ::= <IDENTIFIER> | <IDENTIFIER> "asc" | <IDENTIFIER> "desc"
+  Limit ::= "limit" "(" <INT> ")" |
+            "limit" "(" <INT> "," "offset" <INT> ")"
+
+  NOTE: Each Clause can only appear once
+*/
+func (p *Parser) ParseQuery() interface{} {
+	// Input too Long?
+	if e := p.checkInputLength(); e != nil { // YES: Don't Parse
+		return e
+	}
+
+	q := &ast.Query{}
+
+	for p.curToken.Type == token.IDENT {
+		name := p.curToken
+		p.nextToken()
+
+		var e *ast.ParseError
+		switch strings.ToLower(name.Literal) {
+		case "fields":
+			if q.Fields != nil {
+				return &ast.ParseError{Message: "QUERY: duplicate fields clause"}
+			}
+			q.Fields, e = p.parseFields()
+		case "group":
+			if q.Group != nil {
+				return &ast.ParseError{Message: "QUERY: duplicate group clause"}
+			}
+			q.Group, e = p.parseGroup()
+		case "having":
+			if q.Having != nil {
+				return &ast.ParseError{Message: "QUERY: duplicate having clause"}
+			}
+			q.Having, e = p.parseHaving()
+		case "sort":
+			if q.Sort != nil {
+				return &ast.ParseError{Message: "QUERY: duplicate sort clause"}
+			}
+			q.Sort, e = p.parseSort()
+		case "limit":
+			if q.Limit != nil {
+				return &ast.ParseError{Message: "QUERY: duplicate limit clause"}
+			}
+			q.Limit, e = p.parseLimit()
+		default:
+			if q.Filter != nil {
+				return &ast.ParseError{Message: "QUERY: duplicate filter clause"}
+			}
+
+			f := p.parseFunction(name)
+
+			// Parsed Function without Errors?
+			fast, ok := f.(*ast.Function)
+			if !ok { // NO: Stop Parsing
+				return f
+			}
+			q.Filter = &ast.Filter{F: fast}
+		}
+
+		if e != nil {
+			return e
+		}
+	}
+
+	// Reached End of Line?
+	if p.curToken.Type != token.EOL { // NO: Error
+		return &ast.ParseError{Message: "QUERY: expecting clause name"}
+	}
+
+	// Empty Query?
+	if q.Fields == nil && q.Filter == nil && q.Group == nil && q.Having == nil && q.Sort == nil && q.Limit == nil { // YES
+		return &ast.ParseError{Message: "QUERY: expecting clause name"}
+	}
+
+	return q
+}
+
+func (p *Parser) parseFields() (*ast.Fields, *ast.ParseError) {
+	// Expecting "("
+	if p.curToken.Type != token.LPAREN { // NOT FOUND
+		return nil, &ast.ParseError{Message: "FIELDS: expecting \"(\""}
+	}
+
+	f := &ast.Fields{Fields: make([]token.Token, 0)}
+	for p.nextToken(); ; p.nextToken() {
+		// Expecting Field or Aggregate IDENTIFIER
+		if p.curToken.Type != token.IDENT { // NOT FOUND
+			return nil, &ast.ParseError{Message: "FIELDS: expecting field IDENTIFIER"}
+		}
+
+		name := p.nextToken()
+
+		// Is Aggregate Function?
+		if p.curToken.Type == token.LPAREN { // YES
+			a, e := p.parseAggregate(name)
+			if e != nil {
+				return nil, e
+			}
+			f.Aggregates = append(f.Aggregates, a)
+		} else { // NO: Plain Field
+			f.Fields = append(f.Fields, name)
+		}
+
+		// More Fields?
+		if p.curToken.Type != token.COMMA { // NO
+			break
+		}
+	}
+
+	// Expecting ")"
+	if p.curToken.Type != token.RPAREN { // NOT FOUND
+		return nil, &ast.ParseError{Message: "FIELDS: expecting \")\""}
+	}
+
+	// Consume RPAREN
+	p.nextToken()
+	return f, nil
+}
+
+func (p *Parser) parseAggregate(name token.Token) (*ast.Aggregate, *ast.ParseError) {
+	a := &ast.Aggregate{Function: name}
+
+	// Consume LPAREN
+	p.nextToken()
+
+	// Have Field?
+	if p.curToken.Type == token.IDENT { // YES
+		field := p.nextToken()
+		a.Field = &field
+	}
+
+	// Expecting ")"
+	if p.curToken.Type != token.RPAREN { // NOT FOUND
+		return nil, &ast.ParseError{Message: "FIELDS: expecting aggregate \")\""}
+	}
+
+	// Consume RPAREN
+	p.nextToken()
+	return a, nil
+}
+
+func (p *Parser) parseGroup() (*ast.Group, *ast.ParseError) {
+	// Expecting "("
+	if p.curToken.Type != token.LPAREN { // NOT FOUND
+		return nil, &ast.ParseError{Message: "GROUP: expecting \"(\""}
+	}
+
+	g := &ast.Group{Fields: make([]token.Token, 0)}
+	for p.nextToken(); ; p.nextToken() {
+		// Expecting Field IDENTIFIER
+		if p.curToken.Type != token.IDENT { // NOT FOUND
+			return nil, &ast.ParseError{Message: "GROUP: expecting field IDENTIFIER"}
+		}
+
+		g.Fields = append(g.Fields, p.nextToken())
+
+		// More Fields?
+		if p.curToken.Type != token.COMMA { // NO
+			break
+		}
+	}
+
+	// Expecting ")"
+	if p.curToken.Type != token.RPAREN { // NOT FOUND
+		return nil, &ast.ParseError{Message: "GROUP: expecting \")\""}
+	}
+
+	// Consume RPAREN
+	p.nextToken()
+	return g, nil
+}
+
+func (p *Parser) parseHaving() (*ast.Filter, *ast.ParseError) {
+	// Expecting "("
+	if p.curToken.Type != token.LPAREN { // NOT FOUND
+		return nil, &ast.ParseError{Message: "HAVING: expecting \"(\""}
+	}
+
+	// Consume LPAREN
+	p.nextToken()
+
+	// Expecting Function Name
+	if p.curToken.Type != token.IDENT { // NOT FOUND
+		return nil, &ast.ParseError{Message: "HAVING: expecting function name"}
+	}
+
+	f := p.parseFunction(p.nextToken())
+
+	// Parsed Function without Errors?
+	fast, ok := f.(*ast.Function)
+	if !ok { // NO: Stop Parsing
+		return nil, f.(*ast.ParseError)
+	}
+
+	// Expecting ")"
+	if p.curToken.Type != token.RPAREN { // NOT FOUND
+		return nil, &ast.ParseError{Message: "HAVING: expecting \")\""}
+	}
+
+	// Consume RPAREN
+	p.nextToken()
+	return &ast.Filter{F: fast}, nil
+}
+
+func (p *Parser) parseSort() (*ast.Sort, *ast.ParseError) {
+	// Expecting "("
+	if p.curToken.Type != token.LPAREN { // NOT FOUND
+		return nil, &ast.ParseError{Message: "SORT: expecting \"(\""}
+	}
+
+	s := &ast.Sort{Fields: make([]*ast.SortField, 0)}
+	for p.nextToken(); ; p.nextToken() {
+		// Expecting Field IDENTIFIER
+		if p.curToken.Type != token.IDENT { // NOT FOUND
+			return nil, &ast.ParseError{Message: "SORT: expecting field IDENTIFIER"}
+		}
+
+		sf := &ast.SortField{Field: p.nextToken()}
+
+		// Have Sort Direction?
+		if p.curToken.Type == token.IDENT { // YES
+			switch strings.ToLower(p.curToken.Literal) {
+			case "asc":
+			case "desc":
+				sf.Descending = true
+			default:
+				return nil, &ast.ParseError{Message: fmt.Sprintf("SORT: unexpected direction [%s]", p.curToken.Literal)}
+			}
+			p.nextToken()
+		}
+
+		s.Fields = append(s.Fields, sf)
+
+		// More Fields?
+		if p.curToken.Type != token.COMMA { // NO
+			break
+		}
+	}
+
+	// Expecting ")"
+	if p.curToken.Type != token.RPAREN { // NOT FOUND
+		return nil, &ast.ParseError{Message: "SORT: expecting \")\""}
+	}
+
+	// Consume RPAREN
+	p.nextToken()
+	return s, nil
+}
+
+func (p *Parser) parseLimit() (*ast.Limit, *ast.ParseError) {
+	// Expecting "("
+	if p.curToken.Type != token.LPAREN { // NOT FOUND
+		return nil, &ast.ParseError{Message: "LIMIT: expecting \"(\""}
+	}
+
+	// Consume LPAREN
+	p.nextToken()
+
+	// Expecting Row Count
+	if p.curToken.Type != token.INT { // NOT FOUND
+		return nil, &ast.ParseError{Message: "LIMIT: expecting row count INT"}
+	}
+
+	l := &ast.Limit{Count: p.nextToken()}
+
+	// Have Offset?
+	if p.curToken.Type == token.COMMA { // YES
+		p.nextToken()
+
+		// Expecting "offset" Keyword
+		if p.curToken.Type != token.IDENT || strings.ToLower(p.curToken.Literal) != "offset" { // NOT FOUND
+			return nil, &ast.ParseError{Message: "LIMIT: expecting \"offset\""}
+		}
+		p.nextToken()
+
+		// Expecting Offset Value
+		if p.curToken.Type != token.INT { // NOT FOUND
+			return nil, &ast.ParseError{Message: "LIMIT: expecting offset INT"}
+		}
+
+		offset := p.nextToken()
+		l.Offset = &offset
+	}
+
+	// Expecting ")"
+	if p.curToken.Type != token.RPAREN { // NOT FOUND
+		return nil, &ast.ParseError{Message: "LIMIT: expecting \")\""}
+	}
+
+	// Consume RPAREN
+	p.nextToken()
+	return l, nil
+}
@@ -0,0 +1,110 @@
+package parser
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+)
+
+// Parse Query (returns Query String or Error Message)
+func parseQuery(input string) string {
+	r := NewParser(lexer.NewLexer(input)).ParseQuery()
+
+	switch v := r.(type) {
+	case *ast.Query:
+		return v.ToString()
+	case *ast.ParseError:
+		return v.Message
+	}
+	return ""
+}
+
+func TestSortAndLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`sort(a)`, "sort ( a )"},
+		{`limit(5)`, "limit ( 5 )"},
+		{`SORT(A ASC) LIMIT(1)`, "sort ( A ) limit ( 1 )"},
+		{`eq(a, 1) sort(a desc, b) limit(10, offset 20)`, "eq ( a, 1 ) sort ( a desc, b ) limit ( 10, offset 20 )"},
+		{`limit(10) sort(b) eq(a, 1)`, "eq ( a, 1 ) sort ( b ) limit ( 10 )"},
+		{`sort(a up)`, "SORT: unexpected direction [up]"},
+		{`sort()`, "SORT: expecting field IDENTIFIER"},
+		{`sort(a`, "SORT: expecting \")\""},
+		{`limit("a")`, "LIMIT: expecting row count INT"},
+		{`limit(1, 2)`, "LIMIT: expecting \"offset\""},
+		{`limit(1, offset "x")`, "LIMIT: expecting offset INT"},
+		{`limit(1`, "LIMIT: expecting \")\""},
+		{`sort(a) sort(b)`, "QUERY: duplicate sort clause"},
+		{`limit(1) limit(2)`, "QUERY: duplicate limit clause"},
+		{`eq(a, 1) eq(b, 2)`, "QUERY: duplicate filter clause"},
+		{``, "QUERY: expecting clause name"},
+		{`sort(a) 1`, "QUERY: expecting clause name"},
+	}
+
+	for i, tt := range tests {
+		got := parseQuery(tt.input)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - query wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fields(a)`, "fields ( a )"},
+		{`fields(a, owner.email) eq(a, 1)`, "fields ( a, owner.email ) eq ( a, 1 )"},
+		{`sort(a) fields(b)`, "fields ( b ) sort ( a )"},
+		{`fields()`, "FIELDS: expecting field IDENTIFIER"},
+		{`fields(a, 1)`, "FIELDS: expecting field IDENTIFIER"},
+		{`fields(a b)`, "FIELDS: expecting \")\""},
+		{`fields a`, "FIELDS: expecting \"(\""},
+		{`fields(a) fields(b)`, "QUERY: duplicate fields clause"},
+	}
+
+	for i, tt := range tests {
+		got := parseQuery(tt.input)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - query wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestAggregation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fields(a, count()) group(a)`, "fields ( a, count() ) group ( a )"},
+		{`fields(a, sum(n), max(n)) group(a) having(gt(sum_n, 10))`, "fields ( a, sum(n), max(n) ) group ( a ) having ( gt ( sum_n, 10 ) )"},
+		{`fields(count(a b))`, "FIELDS: expecting aggregate \")\""},
+		{`group()`, "GROUP: expecting field IDENTIFIER"},
+		{`group(a`, "GROUP: expecting \")\""},
+		{`having(1)`, "HAVING: expecting function name"},
+		{`having(eq(a, 1)`, "HAVING: expecting \")\""},
+		{`group(a) group(b)`, "QUERY: duplicate group clause"},
+		{`having(eq(a, 1)) having(eq(a, 2))`, "QUERY: duplicate having clause"},
+	}
+
+	for i, tt := range tests {
+		got := parseQuery(tt.input)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - query wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
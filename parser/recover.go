@@ -0,0 +1,196 @@
+package parser
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/limits"
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  TOLERANT PARSING (Editors)
+  Never stops at the 1st error, instead it:
+  - inserts missing ")" and ","
+  - skips ILLEGAL and unexpected tokens
+  - replaces missing parameters with *ast.ParseError nodes
+  and records a diagnostic (with position) for every repair.
+
+  eq(name "a"        => eq(name, "a") + [missing ",", missing ")"]
+  and(eq(a, ), #)    => and(eq(a, ERROR), ...) + [missing parameter, illegal token]
+
+  The returned filter is best effort, it should be run through the
+  Syntax Checker before use (error nodes fail verification).
+*/
+
+// Parse Filter, Recovering from Errors
+// NOTE: The Filter is never nil, but Filter.F is nil if no function was found
+func (p *Parser) ParseFilterTolerant() (*ast.Filter, []*ast.ParseError) {
+	diagnostics := make([]*ast.ParseError, 0)
+	f := &ast.Filter{}
+
+	// Input too Long?
+	if e := p.checkInputLength(); e != nil { // YES: Don't Parse
+		e.Start, e.End = 0, p.l.Length()
+		return f, append(diagnostics, e)
+	}
+
+	// Skip to 1st Identifier
+	for p.curToken.Type != token.IDENT && p.curToken.Type != token.EOL {
+		diagnostics = append(diagnostics, p.diagnostic("FILTER: expecting function name"))
+		p.nextToken()
+	}
+
+	// Found Function?
+	if p.curToken.Type == token.EOL { // NO: Empty Filter
+		if len(diagnostics) == 0 {
+			diagnostics = append(diagnostics, p.diagnostic("FILTER: expecting function name"))
+		}
+		return f, diagnostics
+	}
+
+	start := p.curSpan[0]
+	name := p.nextToken()
+	f.F, diagnostics = p.tolerantFunction(name, start, diagnostics)
+
+	// Trailing Tokens?
+	if p.curToken.Type != token.EOL { // YES: Ignore Them
+		e := p.diagnostic("FILTER: End-of-line Expected")
+		for ; p.curToken.Type != token.EOL; p.nextToken() {
+			e.End = p.curSpan[1]
+		}
+		diagnostics = append(diagnostics, e)
+	}
+
+	return f, diagnostics
+}
+
+// Parse Function (p.curToken is the Token after the Function Name, start - Position of the Function Name)
+func (p *Parser) tolerantFunction(name token.Token, start int, diagnostics []*ast.ParseError) (*ast.Function, []*ast.ParseError) {
+	f := &ast.Function{Name: name, Parameters: make([]interface{}, 0), Start: start, End: start + len([]rune(name.Literal))}
+
+	// Track Nesting
+	p.depth++
+	defer func() { p.depth-- }()
+
+	// Nested too Deep or too Many Nodes?
+	if e := p.checkNode(); e != nil { // YES: Skip Rest of Input
+		e.Start, e.End = p.curSpan[0], p.l.Length()
+		for ; p.curToken.Type != token.EOL; p.nextToken() {
+		}
+		f.End = e.End
+		return f, append(diagnostics, e)
+	}
+
+	// Expecting "("
+	if p.curToken.Type != token.LPAREN { // NOT FOUND: Function without Parameters
+		return f, append(diagnostics, p.insertion("FUNCTION: expecting function \"(\""))
+	}
+	p.nextToken()
+
+	// Previous Token was a Separator?
+	separated := true
+	for {
+		switch p.curToken.Type {
+		case token.RPAREN:
+			f.End = p.curSpan[1]
+
+			// Trailing ','?
+			if !separated || len(f.Parameters) == 0 { // NO
+				p.nextToken()
+				return f, diagnostics
+			}
+
+			e := p.insertion("FUNCTION PARAMS: missing parameter")
+			f.Parameters = append(f.Parameters, e)
+			diagnostics = append(diagnostics, e)
+			p.nextToken()
+			return f, diagnostics
+		case token.EOL:
+			f.End = p.l.Length()
+
+			// Rest of Input Skipped by a Nesting or Node Limit?
+			if n := len(diagnostics); n > 0 && (diagnostics[n-1].Code == limits.ERR_DEPTH || diagnostics[n-1].Code == limits.ERR_NODES) { // YES: Already Reported
+				return f, diagnostics
+			}
+			return f, append(diagnostics, p.insertion("FUNCTION: expecting function \")\""))
+		case token.COMMA:
+			// Parameter Missing?
+			if separated { // YES: Replace with Error Node
+				e := p.insertion("FUNCTION PARAMS: missing parameter")
+				f.Parameters = append(f.Parameters, e)
+				diagnostics = append(diagnostics, e)
+			}
+			separated = true
+			p.nextToken()
+			continue
+		case token.ILLEGAL:
+			// Unterminated String? (Lexer drops the Opening Quote from the Literal)
+			if p.curSpan[1]-p.curSpan[0] > len([]rune(p.curToken.Literal)) { // YES
+				diagnostics = append(diagnostics, p.diagnostic("FUNCTION PARAMS: missing closing quote"))
+			} else {
+				diagnostics = append(diagnostics, p.diagnostic(fmt.Sprintf("FUNCTION PARAMS: illegal token [%s]", p.curToken.Literal)))
+			}
+			p.nextToken()
+			continue
+		case token.LPAREN:
+			// Function without Name: Parse it as an Unnamed Function
+			diagnostics = append(diagnostics, p.insertion("FUNCTION: expecting function name"))
+		}
+
+		// Missing ','?
+		if !separated { // YES: Insert it
+			diagnostics = append(diagnostics, p.insertion("FUNCTION PARAMS: expecting \",\""))
+		}
+
+		// Parse Parameter
+		if p.curToken.Type == token.LPAREN {
+			var pf *ast.Function
+			pf, diagnostics = p.tolerantFunction(token.Token{Type: token.IDENT, Literal: ""}, p.curSpan[0], diagnostics)
+			f.Parameters = append(f.Parameters, pf)
+		} else if p.curToken.Type == token.IDENT && p.peekToken.Type == token.LPAREN {
+			var pf *ast.Function
+			start := p.curSpan[0]
+			pf, diagnostics = p.tolerantFunction(p.nextToken(), start, diagnostics)
+			f.Parameters = append(f.Parameters, pf)
+		} else {
+			v := &ast.Value{V: p.curToken}
+			if e := p.checkValue(v); e != nil {
+				e.Start, e.End = p.curSpan[0], p.curSpan[1]
+				diagnostics = append(diagnostics, e)
+
+				// Too Many Nodes? YES: Skip Rest of Input
+				if e.Code == limits.ERR_NODES {
+					e.End = p.l.Length()
+					for ; p.curToken.Type != token.EOL; p.nextToken() {
+					}
+					f.End = e.End
+					return f, diagnostics
+				}
+			}
+			f.Parameters = append(f.Parameters, v)
+			p.nextToken()
+		}
+		separated = false
+	}
+}
+
+// Diagnostic at Current Token
+func (p *Parser) diagnostic(message string) *ast.ParseError {
+	return &ast.ParseError{Message: message, Start: p.curSpan[0], End: p.curSpan[1]}
+}
+
+// Diagnostic for Missing Text (Zero Width, before Current Token)
+func (p *Parser) insertion(message string) *ast.ParseError {
+	return &ast.ParseError{Message: message, Start: p.curSpan[0], End: p.curSpan[0]}
+}
@@ -0,0 +1,149 @@
+package parser
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/limits"
+)
+
+// Diagnostics as "[start,end] message" (Separated by "; ")
+func diagnostics(l []*ast.ParseError) string {
+	s := make([]string, 0, len(l))
+	for _, e := range l {
+		s = append(s, fmt.Sprintf("[%d,%d] %s", e.Start, e.End, e.Message))
+	}
+	return strings.Join(s, "; ")
+}
+
+func TestParseFilterTolerant(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    string
+		diagnostics string
+	}{
+		{`eq(a, 1)`, `eq ( a, 1 )`, ""},
+		{`eq(name "a"`, `eq ( name, "a" )`, `[8,8] FUNCTION PARAMS: expecting ","; [11,11] FUNCTION: expecting function ")"`},
+		{`and(eq(a, 1) eq(b, 2))`, `and ( eq ( a, 1 ), eq ( b, 2 ) )`, `[13,13] FUNCTION PARAMS: expecting ","`},
+		{`eq(, 1)`, `eq ( ERROR [FUNCTION PARAMS: missing parameter], 1 )`, "[3,3] FUNCTION PARAMS: missing parameter"},
+		{`eq(a, 1,)`, `eq ( a, 1, ERROR [FUNCTION PARAMS: missing parameter] )`, "[8,8] FUNCTION PARAMS: missing parameter"},
+		{`and(eq(a, ), #)`, `and ( eq ( a, ERROR [FUNCTION PARAMS: missing parameter] ), ERROR [FUNCTION PARAMS: missing parameter] )`,
+			"[10,10] FUNCTION PARAMS: missing parameter; [13,14] FUNCTION PARAMS: illegal token [#]; [14,14] FUNCTION PARAMS: missing parameter"},
+		{`eq(a, "abc`, `eq ( a )`, `[6,10] FUNCTION PARAMS: missing closing quote; [10,10] FUNCTION: expecting function ")"`},
+		{`eq`, `eq (  )`, `[2,2] FUNCTION: expecting function "("`},
+		{`and((eq(a,1)), eq(b, 2))`, `and (  ( eq ( a, 1 ) ), eq ( b, 2 ) )`, "[4,4] FUNCTION: expecting function name"},
+		{`) eq(a, 1)`, `eq ( a, 1 )`, "[0,1] FILTER: expecting function name"},
+		{`eq(a, 1) x y`, `eq ( a, 1 )`, "[9,12] FILTER: End-of-line Expected"},
+		{``, "nil", "[0,0] FILTER: expecting function name"},
+		{`eq(ação, "ü"`, `eq ( ação, "ü" )`, `[12,12] FUNCTION: expecting function ")"`},
+	}
+
+	for i, tt := range tests {
+		f, d := NewParser(lexer.NewLexer(tt.input)).ParseFilterTolerant()
+
+		if f == nil {
+			t.Fatalf("tests[%d] - filter is nil", i)
+		}
+		if got := f.ToString(); got != tt.expected {
+			t.Fatalf("tests[%d] - filter wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+		if got := diagnostics(d); got != tt.diagnostics {
+			t.Fatalf("tests[%d] - diagnostics wrong. expected=%q, got=%q", i, tt.diagnostics, got)
+		}
+	}
+}
+
+func TestErrorNodes(t *testing.T) {
+	f, d := NewParser(lexer.NewLexer(`and(eq(, 1), eq(b, ))`)).ParseFilterTolerant()
+	if len(d) != 2 {
+		t.Fatalf("diagnostics wrong. expected=2, got=%d (%s)", len(d), diagnostics(d))
+	}
+
+	// Error Nodes Replace the Missing Parameters, and are the Diagnostics
+	lhs := f.F.Parameters[0].(*ast.Function)
+	rhs := f.F.Parameters[1].(*ast.Function)
+
+	if e, ok := lhs.Parameters[0].(*ast.ParseError); !ok || e != d[0] {
+		t.Fatalf("lhs parameter 1 wrong. got=%T", lhs.Parameters[0])
+	}
+	if _, ok := lhs.Parameters[1].(*ast.Value); !ok {
+		t.Fatalf("lhs parameter 2 wrong. got=%T", lhs.Parameters[1])
+	}
+	if _, ok := rhs.Parameters[0].(*ast.Value); !ok {
+		t.Fatalf("rhs parameter 1 wrong. got=%T", rhs.Parameters[0])
+	}
+	if e, ok := rhs.Parameters[1].(*ast.ParseError); !ok || e != d[1] {
+		t.Fatalf("rhs parameter 2 wrong. got=%T", rhs.Parameters[1])
+	}
+}
+
+func TestTolerantLimits(t *testing.T) {
+	tests := []struct {
+		input       string
+		limits      *limits.Limits
+		diagnostics string
+	}{
+		{`eq(a, 1)`, &limits.Limits{MaxInputLength: 4}, "[0,8] FILTER: input exceeds maximum length [4]"},
+		{`not(not(eq(a, 1)))`, &limits.Limits{MaxDepth: 2}, "[10,18] FUNCTION: exceeds maximum nesting [2]"},
+		{`eq(a, "abcdef")`, &limits.Limits{MaxStringLength: 3}, "[6,14] FUNCTION PARAMS: string exceeds maximum length [3]"},
+		{`eq(a, "abcdef"`, &limits.Limits{MaxStringLength: 3}, `[6,14] FUNCTION PARAMS: string exceeds maximum length [3]; [14,14] FUNCTION: expecting function ")"`},
+		{`and(eq(a, 1), eq(b, 2))`, &limits.Limits{MaxNodes: 3}, "[10,23] FILTER: exceeds maximum number of nodes [3]"},
+	}
+
+	for i, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		p.Limits = tt.limits
+
+		_, d := p.ParseFilterTolerant()
+		if got := diagnostics(d); got != tt.diagnostics {
+			t.Fatalf("tests[%d] - diagnostics wrong. expected=%q, got=%q", i, tt.diagnostics, got)
+		}
+	}
+}
+
+func TestFunctionSpans(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Function Spans, Depth First
+	}{
+		{`eq(a, 1)`, "eq[0,8]"},
+		{`and(eq(a, 1), not(gt(b, 2)))`, "and[0,28] eq[4,12] not[14,27] gt[18,26]"},
+		{`and(eq(a, 1), gt(b, 2)`, "and[0,22] eq[4,12] gt[14,22]"},
+		{`eq`, "eq[0,2]"},
+		{`and((eq(a,1)), eq(b, 2))`, "and[0,24] [4,13] eq[5,12] eq[15,23]"},
+		{`eq(ação, "ü")`, "eq[0,13]"},
+	}
+
+	for i, tt := range tests {
+		f, _ := NewParser(lexer.NewLexer(tt.input)).ParseFilterTolerant()
+
+		spans := make([]string, 0)
+		var walk func(f *ast.Function)
+		walk = func(f *ast.Function) {
+			spans = append(spans, fmt.Sprintf("%s[%d,%d]", f.Name.Literal, f.Start, f.End))
+			for _, pi := range f.Parameters {
+				if pf, ok := pi.(*ast.Function); ok {
+					walk(pf)
+				}
+			}
+		}
+		walk(f.F)
+
+		if got := strings.Join(spans, " "); got != tt.expected {
+			t.Fatalf("tests[%d] - spans wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package policy
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/builder"
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  ROW LEVEL SECURITY
+  - The policy filter is mandatory, the user filter can only narrow it:
+    Apply(user) == and(policy, user)
+  - User filters can't reference restricted fields (as operands or as
+    @field references)
+  - Macros should be expanded, and parameters bound, before the policy is
+    applied (user filters with macros or parameters are rejected)
+  - Both filters are copied, so later changes to the user filter (or
+    its AST) can't alter the policy in the combined filter
+*/
+
+// Policy Error Object
+type PolicyError struct {
+	Message string
+}
+
+func (e *PolicyError) ToString() string {
+	return e.Message
+}
+
+// Policy Object
+type Policy struct {
+	Filter     *ast.Filter
+	restricted map[string]bool
+}
+
+func NewPolicy(f *ast.Filter, restricted ...string) *Policy {
+	p := &Policy{Filter: f, restricted: make(map[string]bool)}
+	for _, field := range restricted {
+		p.restricted[strings.ToLower(field)] = true
+	}
+	return p
+}
+
+func (p *Policy) IsRestricted(field string) bool {
+	return p.restricted[strings.ToLower(field)]
+}
+
+// Combine Policy and User Filter (User Filter can be nil)
+func (p *Policy) Apply(user *ast.Filter) (*ast.Filter, *PolicyError) {
+	if p.Filter == nil || p.Filter.F == nil {
+		return nil, &PolicyError{Message: "Policy has no filter"}
+	}
+
+	// Have User Filter?
+	if user == nil || user.F == nil { // NO: Only the Policy Applies
+		return p.Filter.Clone(), nil
+	}
+
+	if e := p.verifyFunction(user.F); e != nil {
+		return nil, e
+	}
+
+	return builder.ASTFilter(builder.ASTAND(p.Filter.F.Clone(), user.F.Clone())), nil
+}
+
+// Does the Filter Enforce the Policy (i.e. is the Result of Apply)?
+func (p *Policy) Enforced(f *ast.Filter) bool {
+	if f == nil || f.F == nil || p.Filter == nil || p.Filter.F == nil {
+		return false
+	}
+
+	// Policy Only?
+	if equalFunction(f.F, p.Filter.F) { // YES
+		return true
+	}
+
+	// AND(policy, user)?
+	if !strings.EqualFold(f.F.Name.Literal, "AND") || len(f.F.Parameters) != 2 {
+		return false
+	}
+
+	pf, ok := f.F.Parameters[0].(*ast.Function)
+	return ok && equalFunction(pf, p.Filter.F)
+}
+
+func (p *Policy) verifyFunction(f *ast.Function) *PolicyError {
+	if strings.EqualFold(f.Name.Literal, "MACRO") {
+		return &PolicyError{Message: "Filter has unexpanded macros"}
+	}
+
+	for _, pi := range f.Parameters {
+		switch pv := pi.(type) {
+		case *ast.Function:
+			if e := p.verifyFunction(pv); e != nil {
+				return e
+			}
+		case *ast.Value:
+			// Is Unbound Parameter?
+			if pv.V.Type == token.PARAM { // YES: Binding could Reference a Restricted Field (i.e. @field)
+				return &PolicyError{Message: fmt.Sprintf("Parameter [$%s] is not bound", pv.V.Literal)}
+			}
+
+			// Is Field Reference to Restricted Field?
+			if (pv.V.Type == token.IDENT || pv.V.Type == token.FIELD) && p.IsRestricted(pv.V.Literal) { // YES
+				return &PolicyError{Message: fmt.Sprintf("Field [%s] is restricted", strings.ToLower(pv.V.Literal))}
+			}
+		default:
+			return &PolicyError{Message: fmt.Sprintf("Function [%s] has invalid parameter", f.Name.Literal)}
+		}
+	}
+
+	return nil
+}
+
+// Compare Functions (Function and Field Names are Case Insensitive)
+func equalFunction(f1 *ast.Function, f2 *ast.Function) bool {
+	if !strings.EqualFold(f1.Name.Literal, f2.Name.Literal) || len(f1.Parameters) != len(f2.Parameters) {
+		return false
+	}
+
+	for i := range f1.Parameters {
+		switch p1 := f1.Parameters[i].(type) {
+		case *ast.Function:
+			p2, ok := f2.Parameters[i].(*ast.Function)
+			if !ok || !equalFunction(p1, p2) {
+				return false
+			}
+		case *ast.Value:
+			p2, ok := f2.Parameters[i].(*ast.Value)
+			if !ok || !equalValue(p1, p2) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalValue(v1 *ast.Value, v2 *ast.Value) bool {
+	if v1.V.Type != v2.V.Type {
+		return false
+	}
+
+	if v1.V.Type == token.IDENT || v1.V.Type == token.FIELD {
+		return strings.EqualFold(v1.V.Literal, v2.V.Literal)
+	}
+	return v1.V.Literal == v2.V.Literal
+}
@@ -0,0 +1,132 @@
+package policy
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+)
+
+// Parse Input (Fails Test if Input is not a Valid Filter)
+func parseFilter(t *testing.T, input string) *ast.Filter {
+	r := parser.NewParser(lexer.NewLexer(input)).ParseFilter()
+
+	f, ok := r.(*ast.Filter)
+	if !ok {
+		t.Fatalf("parse [%s] failed. got=%T (%+v)", input, r, r)
+	}
+	return f
+}
+
+func testPolicy(t *testing.T) *Policy {
+	return NewPolicy(parseFilter(t, `eq(tenant, 42)`), "tenant", "Secret")
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{``, "eq ( tenant, 42 )"},
+		{`eq(a, 1)`, "AND ( eq ( tenant, 42 ), eq ( a, 1 ) )"},
+		{`or(eq(a, 1), not(eq(b, @c)))`, "AND ( eq ( tenant, 42 ), or ( eq ( a, 1 ), not ( eq ( b, @c ) ) ) )"},
+	}
+
+	for i, tt := range tests {
+		var user *ast.Filter
+		if tt.input != "" {
+			user = parseFilter(t, tt.input)
+		}
+
+		f, e := testPolicy(t).Apply(user)
+		if e != nil {
+			t.Fatalf("tests[%d] - Apply failed. got=%q", i, e.Message)
+		}
+
+		if got := f.ToString(); got != tt.expected {
+			t.Fatalf("tests[%d] - filter wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`eq(tenant, 1)`, "Field [tenant] is restricted"},
+		{`eq(a, @TENANT)`, "Field [tenant] is restricted"},
+		{`or(eq(a, 1), exists(secret))`, "Field [secret] is restricted"},
+		{`and(eq(a, 1), macro(all))`, "Filter has unexpanded macros"},
+		{`eq(a, $v)`, "Parameter [$v] is not bound"},
+		{`not(eq(a, $1))`, "Parameter [$1] is not bound"},
+	}
+
+	for i, tt := range tests {
+		_, e := testPolicy(t).Apply(parseFilter(t, tt.input))
+		if e == nil || e.Message != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%+v", i, tt.expectedError, e)
+		}
+	}
+
+	if _, e := NewPolicy(nil).Apply(parseFilter(t, `eq(a, 1)`)); e == nil || e.Message != "Policy has no filter" {
+		t.Fatalf("nil policy - error wrong. got=%+v", e)
+	}
+}
+
+// Changes to the User Filter after Apply don't Alter the Result
+func TestApplyCopies(t *testing.T) {
+	p := testPolicy(t)
+	user := parseFilter(t, `eq(a, 1)`)
+
+	f, e := p.Apply(user)
+	if e != nil {
+		t.Fatalf("Apply failed. got=%q", e.Message)
+	}
+
+	user.F.Name.Literal = "neq"
+	p.Filter.F.Parameters[1].(*ast.Value).V.Literal = "0"
+
+	expected := "AND ( eq ( tenant, 42 ), eq ( a, 1 ) )"
+	if got := f.ToString(); got != expected {
+		t.Fatalf("filter wrong. expected=%q, got=%q", expected, got)
+	}
+}
+
+func TestEnforced(t *testing.T) {
+	p := testPolicy(t)
+
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`eq(tenant, 42)`, true},
+		{`EQ(TENANT, 42)`, true},
+		{`and(eq(tenant, 42), eq(a, 1))`, true},
+		{`and(eq(a, 1), eq(tenant, 42))`, false},
+		{`or(eq(tenant, 42), eq(a, 1))`, false},
+		{`eq(tenant, 43)`, false},
+		{`eq(tenant, "42")`, false},
+	}
+
+	for i, tt := range tests {
+		if got := p.Enforced(parseFilter(t, tt.input)); got != tt.expected {
+			t.Fatalf("tests[%d] - enforced wrong. expected=%t, got=%t", i, tt.expected, got)
+		}
+	}
+
+	if p.Enforced(nil) {
+		t.Fatalf("nil filter - expected not enforced")
+	}
+}
@@ -0,0 +1,127 @@
+package printer
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  CANONICAL FORM
+  - Function names in lower case, field names as parsed
+  - No spaces inside parentheses, ", " between parameters
+  - Strings re-escaped, so the output parses back to the same AST
+  - Functions that don't fit in Width are broken over several lines,
+    one parameter per line:
+
+    and(
+      eq(state, "active"),
+      gt(created, 1000)
+    )
+*/
+
+// Default Maximum Line Width
+const DEFAULT_WIDTH = 80
+
+// Printer Object
+type Printer struct {
+	Indent string // Indentation per Level
+	Width  int    // Maximum Line Width (0 - Single Line)
+}
+
+func NewPrinter() *Printer {
+	p := &Printer{Indent: "  ", Width: DEFAULT_WIDTH}
+	return p
+}
+
+func (p *Printer) Print(f *ast.Filter) string {
+	if f == nil || f.F == nil {
+		return ""
+	}
+
+	return p.function(f.F, 0)
+}
+
+func (p *Printer) function(f *ast.Function, level int) string {
+	// Fits on a Single Line?
+	line := Function(f)
+	if p.Width <= 0 || len(p.Indent)*level+len(line) <= p.Width { // YES
+		return line
+	}
+
+	// Any Nested Functions?
+	nested := false
+	for _, pi := range f.Parameters {
+		if _, ok := pi.(*ast.Function); ok {
+			nested = true
+		}
+	}
+
+	if !nested { // NO: Nothing to Break
+		return line
+	}
+
+	indent := strings.Repeat(p.Indent, level+1)
+	params := make([]string, 0, len(f.Parameters))
+	for _, pi := range f.Parameters {
+		params = append(params, indent+p.parameter(pi, level+1))
+	}
+
+	return strings.ToLower(f.Name.Literal) + "(\n" + strings.Join(params, ",\n") + "\n" + strings.Repeat(p.Indent, level) + ")"
+}
+
+func (p *Printer) parameter(pi interface{}, level int) string {
+	switch n := pi.(type) {
+	case *ast.Function:
+		return p.function(n, level)
+	case *ast.Value:
+		return Value(n)
+	}
+	return pi.(ast.Node).ToString()
+}
+
+// Single Line Canonical Form of Function
+func Function(f *ast.Function) string {
+	params := make([]string, 0, len(f.Parameters))
+	for _, pi := range f.Parameters {
+		switch n := pi.(type) {
+		case *ast.Function:
+			params = append(params, Function(n))
+		case *ast.Value:
+			params = append(params, Value(n))
+		default:
+			params = append(params, pi.(ast.Node).ToString())
+		}
+	}
+
+	return strings.ToLower(f.Name.Literal) + "(" + strings.Join(params, ", ") + ")"
+}
+
+// Canonical Form of Value
+// NOTE: Lexer converts '*' to '\uFFFD' (wildcard) and '\*' to '*' (literal)
+func Value(v *ast.Value) string {
+	switch v.V.Type {
+	case token.STRING:
+		s := strings.ReplaceAll(v.V.Literal, `\`, `\\`)
+		s = strings.ReplaceAll(s, `"`, `\"`)
+		s = strings.ReplaceAll(s, "*", `\*`)
+		s = strings.ReplaceAll(s, "\uFFFD", "*")
+		return "\"" + s + "\""
+	case token.FIELD:
+		return "@" + v.V.Literal
+	case token.PARAM:
+		return "$" + v.V.Literal
+	}
+	return v.V.Literal
+}
@@ -0,0 +1,156 @@
+package printer
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+	"github.com/objectvault/filter-parser/token"
+)
+
+// Parse Input (Fails Test if Input is not a Valid Filter)
+func parseFilter(t *testing.T, input string) *ast.Filter {
+	r := parser.NewParser(lexer.NewLexer(input)).ParseFilter()
+
+	f, ok := r.(*ast.Filter)
+	if !ok {
+		t.Fatalf("parse [%s] failed. got=%T (%+v)", input, r, r)
+	}
+	return f
+}
+
+// Printed Filters Parse Back to the Same AST
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`eq( a , "x" )`, `eq(a, "x")`},
+		{`eq(a, "say \"hi\"")`, `eq(a, "say \"hi\"")`},
+		{`eq(a, "c:\\temp\\")`, `eq(a, "c:\\temp\\")`},
+		{`contains(a, "*x*")`, `contains(a, "*x*")`},
+		{`eq(a, "5\*")`, `eq(a, "5\*")`},
+		{`contains(a, "*\**")`, `contains(a, "*\**")`},
+		{`matches(a, "^a\\d+\*$")`, `matches(a, "^a\\d+\*$")`},
+		{`eq(a, "it's")`, `eq(a, "it's")`},
+		{`eq(a, @b)`, `eq(a, @b)`},
+		{`gt(n, $min)`, `gt(n, $min)`},
+		{`between(n, -1, 2.5)`, `between(n, -1, 2.5)`},
+		{`in(a, "x", "y,z")`, `in(a, "x", "y,z")`},
+	}
+
+	for i, tt := range tests {
+		f := parseFilter(t, tt.input)
+		got := NewPrinter().Print(f)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - print wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+
+		// Same AST
+		if rf := parseFilter(t, got); rf.ToString() != f.ToString() {
+			t.Fatalf("tests[%d] - round trip wrong. expected=%q, got=%q", i, f.ToString(), rf.ToString())
+		}
+	}
+
+	// Function Names are Lower Case, Field Names as Parsed
+	if got := NewPrinter().Print(parseFilter(t, `AND(EQ(State, "X"), Not(Exists(b)))`)); got != `and(eq(State, "X"), not(exists(b)))` {
+		t.Fatalf("case wrong. got=%q", got)
+	}
+}
+
+// '\*' is a Literal '*', '*' is a Wildcard
+func TestWildcards(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Literal as Lexed
+	}{
+		{`"a*"`, "a\uFFFD"},
+		{`"a\*"`, "a*"},
+		{`"\**"`, "*\uFFFD"},
+	}
+
+	for i, tt := range tests {
+		v := &ast.Value{V: lexer.NewLexer(tt.input).NextToken()}
+		if v.V.Literal != tt.expected {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expected, v.V.Literal)
+		}
+
+		if got := Value(v); got != tt.input {
+			t.Fatalf("tests[%d] - value wrong. expected=%q, got=%q", i, tt.input, got)
+		}
+	}
+
+	// Values not from the Lexer (i.e. Bound) have no Wildcards
+	v := &ast.Value{V: token.Token{Type: token.STRING, Literal: "a*"}}
+	if got := Value(v); got != `"a\*"` {
+		t.Fatalf("bound value wrong. expected=%q, got=%q", `"a\*"`, got)
+	}
+}
+
+func TestLineBreaking(t *testing.T) {
+	input := `and(eq(state, "active"), or(gt(created, 1000), lt(updated, 2000)))`
+
+	tests := []struct {
+		width    int
+		indent   string
+		expected string
+	}{
+		{0, "  ", input},
+		{len(input), "  ", input},
+		{len(input) - 1, "  ", "and(\n  eq(state, \"active\"),\n  or(gt(created, 1000), lt(updated, 2000))\n)"},
+		{30, "  ", "and(\n  eq(state, \"active\"),\n  or(\n    gt(created, 1000),\n    lt(updated, 2000)\n  )\n)"},
+		{30, "\t", "and(\n\teq(state, \"active\"),\n\tor(\n\t\tgt(created, 1000),\n\t\tlt(updated, 2000)\n\t)\n)"},
+		{10, "  ", "and(\n  eq(state, \"active\"),\n  or(\n    gt(created, 1000),\n    lt(updated, 2000)\n  )\n)"},
+	}
+
+	for i, tt := range tests {
+		p := NewPrinter()
+		p.Width = tt.width
+		p.Indent = tt.indent
+
+		f := parseFilter(t, input)
+		got := p.Print(f)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - print wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+
+		// Line Breaks don't Change the AST
+		if rf := parseFilter(t, got); rf.ToString() != f.ToString() {
+			t.Fatalf("tests[%d] - round trip wrong. expected=%q, got=%q", i, f.ToString(), rf.ToString())
+		}
+	}
+}
+
+// Functions without Nested Functions are never Broken
+func TestLongValues(t *testing.T) {
+	input := `in(state, "active", "archived", "deleted", "pending", "suspended")`
+
+	p := NewPrinter()
+	p.Width = 20
+	if got := p.Print(parseFilter(t, input)); got != input {
+		t.Fatalf("print wrong. expected=%q, got=%q", input, got)
+	}
+
+	// Nested Level Counts towards the Width
+	input = `not(in(state, "active", "archived"))`
+	expected := "not(\n  in(state, \"active\", \"archived\")\n)"
+	p.Width = len(input) - 1
+	if got := p.Print(parseFilter(t, input)); got != expected {
+		t.Fatalf("nested print wrong. expected=%q, got=%q", expected, got)
+	}
+
+	if got := p.Print(nil); got != "" {
+		t.Fatalf("nil filter wrong. got=%q", got)
+	}
+}
@@ -0,0 +1,112 @@
+package schema
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  SCHEMA FILE (JSON)
+  {
+    "fields": [
+      { "name": "state", "type": "string", "values": ["active", "archived"] },
+      { "name": "tags", "type": "string", "collection": true },
+      { "name": "created", "type": "int", "indexed": true, "cardinality": 100000 },
+      { "name": "location", "type": "point", "operators": ["near"] }
+    ]
+  }
+*/
+
+// Schema Error Object
+type SchemaError struct {
+	Message string
+}
+
+func (e *SchemaError) ToString() string {
+	return e.Message
+}
+
+type jsonField struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Collection  bool     `json:"collection"`
+	Operators   []string `json:"operators"`
+	Values      []string `json:"values"`
+	Indexed     bool     `json:"indexed"`
+	Cardinality int      `json:"cardinality"`
+	Selectivity float64  `json:"selectivity"`
+}
+
+type jsonSchema struct {
+	Fields []jsonField `json:"fields"`
+}
+
+// Load Schema from JSON File
+func LoadFile(path string) (*Schema, *SchemaError) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, &SchemaError{Message: fmt.Sprintf("Schema [%s] %s", path, err.Error())}
+	}
+
+	return Parse(data)
+}
+
+// Parse JSON Schema
+func Parse(data []byte) (*Schema, *SchemaError) {
+	var js jsonSchema
+	if err := json.Unmarshal(data, &js); err != nil {
+		return nil, &SchemaError{Message: fmt.Sprintf("Invalid Schema: %s", err.Error())}
+	}
+
+	s := NewSchema()
+	for i, jf := range js.Fields {
+		if jf.Name == "" {
+			return nil, &SchemaError{Message: fmt.Sprintf("Schema Field %d has no name", i+1)}
+		}
+
+		t, ok := fieldType(jf.Type)
+		if !ok {
+			return nil, &SchemaError{Message: fmt.Sprintf("Schema Field [%s] has an invalid type [%s]", jf.Name, jf.Type)}
+		}
+
+		f := s.AddField(jf.Name, t)
+		f.Collection = jf.Collection
+		f.Values = jf.Values
+		f.Indexed = jf.Indexed
+		f.Cardinality = jf.Cardinality
+		f.Selectivity = jf.Selectivity
+		if jf.Operators != nil {
+			f.AllowOperators(jf.Operators...)
+		}
+	}
+
+	return s, nil
+}
+
+func fieldType(name string) (token.TokenType, bool) {
+	switch strings.ToUpper(name) {
+	case "STRING":
+		return token.STRING, true
+	case "INT":
+		return token.INT, true
+	case "NUMBER":
+		return token.NUMBER, true
+	case "POINT":
+		return POINT, true
+	}
+	return "", false
+}
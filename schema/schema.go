@@ -0,0 +1,154 @@
+package schema
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/objectvault/filter-parser/token"
+)
+
+// Geographic Point Field Type (no Literal Form, only usable with NEAR and WITHIN_BOX)
+const POINT token.TokenType = "POINT"
+
+// Field Definition
+type Field struct {
+	Name       string
+	Type       token.TokenType // Value Type (token.STRING, token.INT, token.NUMBER or POINT)
+	Collection bool            // Multi-Valued Field (Type is the Element Type)
+	Operators  []string        // OPTIONAL: Allowed Operators (nil - All Operators Allowed)
+	Values     []string        // OPTIONAL: Known Values (Enumerations, used for Completion)
+
+	// Query Planning Metadata (OPTIONAL)
+	Indexed     bool    // Field has an Index (FULLTEXT for SEARCH)
+	Cardinality int     // Number of Distinct Values (0 - Unknown)
+	Selectivity float64 // Fraction of Rows Matched by Equality (0 - Derive from Cardinality)
+}
+
+// Schema Object (Set of Known Fields)
+type Schema struct {
+	fields map[string]*Field
+}
+
+func NewSchema() *Schema {
+	s := &Schema{fields: make(map[string]*Field)}
+	return s
+}
+
+func (s *Schema) AddField(name string, t token.TokenType) *Field {
+	// Field Names should always be Lower Case
+	name = strings.ToLower(name)
+
+	f := &Field{Name: name, Type: t}
+	s.fields[name] = f
+	return f
+}
+
+func (s *Schema) AddCollection(name string, t token.TokenType) *Field {
+	f := s.AddField(name, t)
+	f.Collection = true
+	return f
+}
+
+// Add a Copy of a Field Definition (i.e. from another Schema) under a Name
+func (s *Schema) CopyField(name string, f *Field) *Field {
+	// Field Names should always be Lower Case
+	name = strings.ToLower(name)
+
+	c := *f
+	c.Name = name
+	s.fields[name] = &c
+	return &c
+}
+
+func (s *Schema) Field(name string) *Field {
+	f, ok := s.fields[strings.ToLower(name)]
+	if !ok {
+		return nil
+	}
+	return f
+}
+
+// All Fields (Sorted by Name)
+func (s *Schema) Fields() []*Field {
+	l := make([]*Field, 0, len(s.fields))
+	for _, f := range s.fields {
+		l = append(l, f)
+	}
+
+	sort.Slice(l, func(i, j int) bool { return l[i].Name < l[j].Name })
+	return l
+}
+
+// Set Known Values for the Field
+func (f *Field) SetValues(values ...string) *Field {
+	f.Values = values
+	return f
+}
+
+// Restrict Operators that can be Applied to the Field
+func (f *Field) AllowOperators(operators ...string) *Field {
+	f.Operators = make([]string, 0, len(operators))
+	for _, op := range operators {
+		f.Operators = append(f.Operators, strings.ToUpper(op))
+	}
+	return f
+}
+
+// Can the Operator be Applied to the Field?
+func (f *Field) Allows(operator string) bool {
+	if f.Operators == nil {
+		return true
+	}
+
+	operator = strings.ToUpper(operator)
+	for _, op := range f.Operators {
+		if op == operator {
+			return true
+		}
+	}
+	return false
+}
+
+// Set Query Planning Metadata
+func (f *Field) SetIndex(indexed bool, cardinality int) *Field {
+	f.Indexed = indexed
+	f.Cardinality = cardinality
+	return f
+}
+
+// Estimated Fraction of Rows Matched by Equality
+func (f *Field) EqualitySelectivity() float64 {
+	if f.Selectivity > 0 {
+		return f.Selectivity
+	}
+
+	if f.Cardinality > 0 {
+		return 1 / float64(f.Cardinality)
+	}
+
+	// Unknown: Assume 10%
+	return 0.1
+}
+
+// Is the Value Type Compatible with the Field Type?
+func (f *Field) Accepts(t token.TokenType) bool {
+	// Any Number can be Compared to another Number
+	if isNumeric(f.Type) && isNumeric(t) {
+		return true
+	}
+	return f.Type == t
+}
+
+func isNumeric(t token.TokenType) bool {
+	return t == token.INT || t == token.NUMBER
+}
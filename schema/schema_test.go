@@ -0,0 +1,216 @@
+package schema
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/objectvault/filter-parser/token"
+)
+
+func TestEqualitySelectivity(t *testing.T) {
+	tests := []struct {
+		cardinality int
+		selectivity float64
+		expected    float64
+	}{
+		{0, 0, 0.1},
+		{4, 0, 0.25},
+		{1000, 0, 0.001},
+		{1000, 0.5, 0.5},
+		{0, 0.02, 0.02},
+	}
+
+	for i, tt := range tests {
+		f := NewSchema().AddField("a", token.STRING).SetIndex(true, tt.cardinality)
+		f.Selectivity = tt.selectivity
+
+		got := f.EqualitySelectivity()
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - selectivity wrong. expected=%v, got=%v", i, tt.expected, got)
+		}
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		field    token.TokenType
+		value    token.TokenType
+		expected bool
+	}{
+		{token.STRING, token.STRING, true},
+		{token.STRING, token.INT, false},
+		{token.INT, token.NUMBER, true},
+		{token.NUMBER, token.INT, true},
+		{token.INT, token.STRING, false},
+		{POINT, token.NUMBER, false},
+	}
+
+	for i, tt := range tests {
+		got := NewSchema().AddField("a", tt.field).Accepts(tt.value)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - accepts wrong. expected=%t, got=%t", i, tt.expected, got)
+		}
+	}
+}
+
+func TestAllows(t *testing.T) {
+	s := NewSchema()
+	s.AddField("a", token.STRING)
+	s.AddField("b", token.STRING).AllowOperators("eq", "In")
+
+	tests := []struct {
+		field    string
+		operator string
+		expected bool
+	}{
+		{"a", "MATCHES", true},
+		{"b", "EQ", true},
+		{"b", "in", true},
+		{"b", "MATCHES", false},
+	}
+
+	for i, tt := range tests {
+		got := s.Field(tt.field).Allows(tt.operator)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - allows wrong. expected=%t, got=%t", i, tt.expected, got)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	s := NewSchema()
+	s.AddField("State", token.STRING)
+	s.AddCollection("tags", token.STRING)
+	s.AddField("created", token.INT)
+
+	if s.Field("STATE") == nil || s.Field("state").Name != "state" {
+		t.Fatalf("field lookup is not case insensitive")
+	}
+	if !s.Field("tags").Collection {
+		t.Fatalf("tags is not a collection")
+	}
+	if s.Field("zz") != nil {
+		t.Fatalf("unknown field found")
+	}
+
+	expected := []string{"created", "state", "tags"}
+	fields := s.Fields()
+	if len(fields) != len(expected) {
+		t.Fatalf("fields wrong. expected=%d, got=%d", len(expected), len(fields))
+	}
+	for i, name := range expected {
+		if fields[i].Name != name {
+			t.Fatalf("fields[%d] - name wrong. expected=%q, got=%q", i, name, fields[i].Name)
+		}
+	}
+}
+
+func TestCopyField(t *testing.T) {
+	s := NewSchema()
+	f := s.AddCollection("tags", token.STRING).AllowOperators("has").SetValues("x", "y")
+
+	c := NewSchema().CopyField("Labels", f)
+	if c.Name != "labels" || c.Type != token.STRING || !c.Collection {
+		t.Fatalf("copy wrong. got=%+v", c)
+	}
+	if c.Allows("EQ") || !c.Allows("HAS") || len(c.Values) != 2 {
+		t.Fatalf("copy lost restrictions. got=%+v", c)
+	}
+
+	// Original is not Modified
+	if f.Name != "tags" {
+		t.Fatalf("original modified. got=%q", f.Name)
+	}
+}
+
+func TestParse(t *testing.T) {
+	data := `{
+		"fields": [
+			{ "name": "state", "type": "string", "values": ["active", "archived"] },
+			{ "name": "tags", "type": "STRING", "collection": true },
+			{ "name": "created", "type": "int", "indexed": true, "cardinality": 100000 },
+			{ "name": "kind", "type": "string", "indexed": true, "selectivity": 0.25 },
+			{ "name": "location", "type": "point", "operators": ["near"] }
+		]
+	}`
+
+	s, e := Parse([]byte(data))
+	if e != nil {
+		t.Fatalf("parse failed. got=%q", e.ToString())
+	}
+
+	tests := []struct {
+		field       string
+		fieldType   token.TokenType
+		collection  bool
+		indexed     bool
+		selectivity float64
+	}{
+		{"state", token.STRING, false, false, 0.1},
+		{"tags", token.STRING, true, false, 0.1},
+		{"created", token.INT, false, true, 0.00001},
+		{"kind", token.STRING, false, true, 0.25},
+		{"location", POINT, false, false, 0.1},
+	}
+
+	for i, tt := range tests {
+		f := s.Field(tt.field)
+		if f == nil {
+			t.Fatalf("tests[%d] - field [%s] missing", i, tt.field)
+		}
+		if f.Type != tt.fieldType {
+			t.Fatalf("tests[%d] - type wrong. expected=%q, got=%q", i, tt.fieldType, f.Type)
+		}
+		if f.Collection != tt.collection {
+			t.Fatalf("tests[%d] - collection wrong. expected=%t, got=%t", i, tt.collection, f.Collection)
+		}
+		if f.Indexed != tt.indexed {
+			t.Fatalf("tests[%d] - indexed wrong. expected=%t, got=%t", i, tt.indexed, f.Indexed)
+		}
+		if got := f.EqualitySelectivity(); got != tt.selectivity {
+			t.Fatalf("tests[%d] - selectivity wrong. expected=%v, got=%v", i, tt.selectivity, got)
+		}
+	}
+
+	if len(s.Field("state").Values) != 2 {
+		t.Fatalf("state values wrong. got=%v", s.Field("state").Values)
+	}
+	if !s.Field("location").Allows("NEAR") || s.Field("location").Allows("EQ") {
+		t.Fatalf("location operators wrong. got=%v", s.Field("location").Operators)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{ "fields": [ { "type": "string" } ] }`, "Schema Field 1 has no name"},
+		{`{ "fields": [ { "name": "a", "type": "date" } ] }`, "Schema Field [a] has an invalid type [date]"},
+		{`{ "fields": [ { "name": "a", "type": "string" }, { "name": "b" } ] }`, "Schema Field [b] has an invalid type []"},
+	}
+
+	for i, tt := range tests {
+		_, e := Parse([]byte(tt.input))
+		if e == nil {
+			t.Fatalf("tests[%d] - expected error %q", i, tt.expected)
+		}
+		if e.ToString() != tt.expected {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expected, e.ToString())
+		}
+	}
+
+	// Invalid JSON
+	if _, e := Parse([]byte(`{ "fields": `)); e == nil {
+		t.Fatalf("invalid json accepted")
+	}
+}
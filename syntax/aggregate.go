@@ -0,0 +1,170 @@
+package syntax
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/schema"
+	"github.com/objectvault/filter-parser/token"
+)
+
+func (c *SyntaxChecker) verifyAggregate(a *ast.Aggregate) *SyntaxError {
+	// Normalize Aggregate Name (ALL UPPERCASE)
+	aname := strings.ToUpper(a.Function.Literal)
+	a.Function.Literal = aname
+
+	switch aname {
+	case "COUNT", "SUM", "AVG", "MIN", "MAX":
+	default:
+		return &SyntaxError{Message: fmt.Sprintf("Aggregate [%s] is not recognized", aname)}
+	}
+
+	// COUNT() counts Rows
+	if a.Field == nil {
+		if aname != "COUNT" {
+			return &SyntaxError{Message: fmt.Sprintf("Aggregate [%s] requires a field", aname)}
+		}
+		return nil
+	}
+
+	// Field Names should always be Lower Case
+	a.Field.Literal = strings.ToLower(a.Field.Literal)
+
+	if e := c.authorizeField(aname, a.Field.Literal); e != nil {
+		return e
+	}
+
+	// Have a Schema to Verify Against?
+	if c.Schema == nil { // NO: Accept any Field
+		return nil
+	}
+
+	f := c.Schema.Field(a.Field.Literal)
+	if f == nil {
+		return &SyntaxError{Message: fmt.Sprintf("Aggregate [%s] Field [%s] is not recognized", aname, a.Field.Literal)}
+	}
+
+	if f.Collection || f.Type == schema.POINT {
+		return &SyntaxError{Message: fmt.Sprintf("Aggregate [%s] Field [%s] can't be aggregated", aname, f.Name)}
+	}
+
+	if (aname == "SUM" || aname == "AVG") && !isNumeric(f.Type) {
+		return &SyntaxError{Message: fmt.Sprintf("Aggregate [%s] Field [%s] is not a Number", aname, f.Name)}
+	}
+
+	return nil
+}
+
+// Verify Group and Having Clauses, Returns the Fields Visible after Grouping
+func (c *SyntaxChecker) verifyAggregation(q *ast.Query) (map[string]*schema.Field, *SyntaxError) {
+	// Fields Visible after Grouping (Group Fields and Aggregate Aliases)
+	visible := make(map[string]*schema.Field)
+
+	// Have Group Clause?
+	if q.Group != nil { // YES: Verify it
+		if len(q.Group.Fields) == 0 {
+			return nil, &SyntaxError{Message: "Group should have at least 1 field"}
+		}
+
+		for i := range q.Group.Fields {
+			g := &q.Group.Fields[i]
+
+			// Field Names should always be Lower Case
+			g.Literal = strings.ToLower(g.Literal)
+
+			if _, ok := visible[g.Literal]; ok {
+				return nil, &SyntaxError{Message: fmt.Sprintf("Group Field [%s] is repeated", g.Literal)}
+			}
+
+			// Have a Schema to Verify Against?
+			f := &schema.Field{Name: g.Literal}
+			if c.Schema != nil { // YES: Field should Exist
+				f = c.Schema.Field(g.Literal)
+				if f == nil {
+					return nil, &SyntaxError{Message: fmt.Sprintf("Group Field [%s] is not recognized", g.Literal)}
+				}
+
+				if f.Collection || f.Type == schema.POINT {
+					return nil, &SyntaxError{Message: fmt.Sprintf("Group Field [%s] can't be grouped", g.Literal)}
+				}
+			}
+
+			if e := c.authorizeField("GROUP", g.Literal); e != nil {
+				return nil, e
+			}
+
+			visible[g.Literal] = f
+		}
+	}
+
+	// Have Projection?
+	if q.Fields != nil { // YES: Plain Fields should be Group Fields
+		for _, f := range q.Fields.Fields {
+			if _, ok := visible[f.Literal]; !ok {
+				return nil, &SyntaxError{Message: fmt.Sprintf("Fields Field [%s] is not a group field", f.Literal)}
+			}
+		}
+
+		for _, a := range q.Fields.Aggregates {
+			visible[a.Alias()] = aggregateField(c.Schema, a)
+		}
+	}
+
+	// Have Having Clause?
+	if q.Having != nil { // YES: Verify it against Visible Fields
+		hc := NewSyntaxChecker(q.Having)
+		hc.Params = c.Params
+		hc.Limits = c.Limits
+		hc.MaxPatternLength = c.MaxPatternLength
+		hc.Authorize = c.Authorize
+		hc.Context = c.Context
+
+		// Have a Schema to Verify Against?
+		if c.Schema != nil { // YES: Having can only use Visible Fields (with their Operator Restrictions)
+			hc.Schema = schema.NewSchema()
+			for name, f := range visible {
+				hc.Schema.CopyField(name, f)
+			}
+		}
+
+		// NOTE: Pattern and Wildcard Limits apply to the Query as a Whole
+		hc.patterns, hc.wildcards = c.patterns, c.wildcards
+		e := hc.verifyFilter(q.Having)
+		c.patterns, c.wildcards = hc.patterns, hc.wildcards
+
+		if e != nil {
+			return nil, &SyntaxError{Code: e.Code, Message: fmt.Sprintf("Having %s", e.Message), Node: e.Node}
+		}
+	}
+
+	return visible, nil
+}
+
+// Schema Field Describing an Aggregate Result
+func aggregateField(s *schema.Schema, a *ast.Aggregate) *schema.Field {
+	f := &schema.Field{Name: a.Alias(), Type: token.NUMBER}
+
+	switch a.Function.Literal {
+	case "COUNT":
+		f.Type = token.INT
+	case "MIN", "MAX":
+		// Same Type as Aggregated Field
+		if s != nil && a.Field != nil {
+			if af := s.Field(a.Field.Literal); af != nil {
+				f.Type = af.Type
+			}
+		}
+	}
+	return f
+}
@@ -12,40 +12,69 @@ package syntax
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/limits"
+	"github.com/objectvault/filter-parser/schema"
 	"github.com/objectvault/filter-parser/token"
 )
 
 // Syntax Error Object
 type SyntaxError struct {
+	Code    string // OPTIONAL: Error Code (i.e. limits.ERR_DEPTH)
 	Message string
+	Node    *ast.Function // OPTIONAL: Innermost Function that Failed Verification
 }
 
 func (e *SyntaxError) ToString() string {
 	return e.Message
 }
 
+// Field Authorization Callback (return false to deny)
+// NOTE: value is nil when the operator has no value (i.e. EXISTS, SORT)
+type TAuthorizeField = func(field string, operator string, value *ast.Value, context interface{}) bool
+
+// Default Maximum Length for MATCHES Regular Expressions
+const DEFAULT_MAX_PATTERN_LENGTH = 256
+
 // Syntax Checker Object
 type SyntaxChecker struct {
-	AST ast.Node
+	AST       ast.Node
+	Schema    *schema.Schema             // OPTIONAL: Known Fields (nil - Fields are not Verified)
+	Params    map[string]token.TokenType // Declared Bind Parameters (name or position to value type)
+	Authorize TAuthorizeField            // OPTIONAL: Field Authorization Callback
+	Context   interface{}                // Caller Context passed to Authorize
+	Limits    *limits.Limits             // OPTIONAL: Resource Limits (nil - Only Nesting and Pattern Length are Bounded)
+	depth     int                        // Current Function Nesting
+	patterns  int                        // Pattern Operators Found
+	wildcards int                        // Wildcards Found
+
+	// Deprecated: Use Limits.MaxPatternLength (only applies while Limits is nil, 0 - No Limit)
+	MaxPatternLength int
 }
 
 func NewSyntaxChecker(root ast.Node) *SyntaxChecker {
-	c := &SyntaxChecker{AST: root}
+	c := &SyntaxChecker{AST: root, MaxPatternLength: DEFAULT_MAX_PATTERN_LENGTH}
 
 	return c
 }
 
 func (c *SyntaxChecker) Verify() *SyntaxError {
-	f, ok := c.AST.(*ast.Filter)
+	// Reset Limit Counters
+	c.depth, c.patterns, c.wildcards = 0, 0, 0
 
-	if !ok {
-		e := &SyntaxError{Message: "Invalid AST Object"}
-		return e
+	switch n := c.AST.(type) {
+	case *ast.Filter:
+		return c.verifyFilter(n)
+	case *ast.Query:
+		return c.verifyQuery(n)
 	}
-	return c.verifyFilter(f)
+
+	e := &SyntaxError{Message: "Invalid AST Object"}
+	return e
 }
 
 func (c *SyntaxChecker) verifyFilter(f *ast.Filter) *SyntaxError {
@@ -53,14 +82,33 @@ func (c *SyntaxChecker) verifyFilter(f *ast.Filter) *SyntaxError {
 	return c.verifyFunction(nil, fu)
 }
 
-func (c *SyntaxChecker) verifyFunction(p *ast.Function, f *ast.Function) *SyntaxError {
-	var e *SyntaxError
+func (c *SyntaxChecker) verifyFunction(p *ast.Function, f *ast.Function) (e *SyntaxError) {
+	// Error Not Located? Locate it at this Function
+	defer func() {
+		if e != nil && e.Node == nil {
+			e.Node = f
+		}
+	}()
 
 	// Normalize Function Name (ALL UPPERCASE)
 	fname := f.Name.Literal
 	fname = strings.ToUpper(fname)
 	f.Name.Literal = fname
 
+	// Track Nesting
+	c.depth++
+	defer func() { c.depth-- }()
+
+	// Within Resource Limits?
+	if e = c.verifyLimits(fname, f); e != nil { // NO
+		return e
+	}
+
+	// Are Bind Parameters Declared?
+	if e = c.verifyParams(fname, f); e != nil { // NO
+		return e
+	}
+
 	t := functionType(fname)
 	switch t {
 	case "logical-unary":
@@ -121,6 +169,18 @@ func (c *SyntaxChecker) verifyFunction(p *ast.Function, f *ast.Function) *Syntax
 		// Field Names should always be Lower Case
 		pv1.V.Literal = strings.ToLower(pv1.V.Literal)
 
+		// CHECK: Parameter 1 is a Known Field
+		var f1 *schema.Field
+		f1, e = c.verifyField(fname, pv1)
+		if e != nil {
+			break
+		}
+
+		if f1 != nil && f1.Collection {
+			e = &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] is a Collection (use HAS, HAS_ANY or HAS_ALL)", fname, f1.Name)}
+			break
+		}
+
 		// CHECK: Parameter 2 should be a Non Identifier Value
 		pv2, ok := f.Parameters[1].(*ast.Value)
 		if !ok {
@@ -129,32 +189,640 @@ func (c *SyntaxChecker) verifyFunction(p *ast.Function, f *ast.Function) *Syntax
 		}
 
 		if pv2.V.Type == token.IDENT {
-			e = &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter 2 should not be an Identifier", fname)}
+			e = &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter 2 should not be an Identifier (use @%s for a Field Reference)", fname, pv2.V.Literal)}
 			break
 		}
 
-		if fname == "CONTAINS" || fname == "IN" {
-			if pv2.V.Type != token.STRING {
-				e = &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter 2 should be a String no [%s]", fname, pv2.V.Type)}
+		switch fname {
+		case "CONTAINS", "STARTSWITH", "ENDSWITH", "ICONTAINS":
+			if c.valueType(pv2) != token.STRING {
+				e = &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter 2 should be a String no [%s]", fname, c.valueType(pv2))}
+			}
+		case "MATCHES":
+			if c.valueType(pv2) != token.STRING {
+				e = &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter 2 should be a String no [%s]", fname, c.valueType(pv2))}
 				break
 			}
+
+			// NOTE: Bound Patterns are Verified by binder.Bind
+			if pv2.V.Type != token.PARAM {
+				e = c.verifyPattern(fname, pv2)
+			}
+		case "IEQ":
+			if c.valueType(pv2) != token.STRING && pv2.V.Type != token.FIELD {
+				e = &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter 2 should be a String or Field Reference no [%s]", fname, c.valueType(pv2))}
+			}
+		}
+
+		if e != nil {
+			break
 		}
 
+		// Is Parameter 2 a Field Reference?
+		if pv2.V.Type == token.FIELD { // YES: Both Fields should Share a Type
+			pv2.V.Literal = strings.ToLower(pv2.V.Literal)
+
+			f2, e2 := c.verifyField(fname, pv2)
+			if e2 != nil {
+				e = e2
+				break
+			}
+
+			if f2 != nil && f2.Collection {
+				e = &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] is a Collection and can't be used as a Field Reference", fname, f2.Name)}
+				break
+			}
+
+			if f1 != nil && f2 != nil && !f1.Accepts(f2.Type) {
+				e = &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] of type [%s] can't be compared to Field [%s] of type [%s]", fname, f1.Name, f1.Type, f2.Name, f2.Type)}
+			}
+			break
+		}
+
+		// Is Value Compatible with Field?
+		if f1 != nil && !f1.Accepts(c.valueType(pv2)) { // NO
+			e = &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] expects a value of type [%s] not [%s]", fname, f1.Name, f1.Type, c.valueType(pv2))}
+		}
+	case "operator-unary":
+		// CHECK :Number of Parameters
+		if len(f.Parameters) != 1 {
+			e = &SyntaxError{Message: fmt.Sprintf("Function [%s] should have 1 parameter, found [%d]", fname, len(f.Parameters))}
+			break
+		}
+
+		// CHECK: Parameter 1 should be an identifier
+		pv1, ok := f.Parameters[0].(*ast.Value)
+		if !ok || pv1.V.Type != token.IDENT {
+			e = &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter 1 is not a Field Identifier", fname)}
+			break
+		}
+
+		// Field Names should always be Lower Case
+		pv1.V.Literal = strings.ToLower(pv1.V.Literal)
+		_, e = c.verifyField(fname, pv1)
+	case "operator-range":
+		e = c.verifyRange(fname, f)
+	case "operator-list":
+		e = c.verifyList(fname, f)
+	case "operator-collection":
+		e = c.verifyCollection(fname, f)
+	case "operator-search":
+		e = c.verifySearch(fname, f)
+	case "operator-geo":
+		e = c.verifyGeo(fname, f)
+	case "macro":
+		e = &SyntaxError{Message: fmt.Sprintf("Function [%s] has not been expanded", fname)}
 	default:
 		e = &SyntaxError{Message: fmt.Sprintf("Function [%s] is not recognized", f.Name.Literal)}
 	}
 
+	// Verified Field Operator?
+	if e == nil && strings.HasPrefix(t, "operator") { // YES: Can the Caller Use it?
+		e = c.authorizeOperator(fname, f)
+	}
+
 	return e
 }
 
+func (c *SyntaxChecker) verifyRange(fname string, f *ast.Function) *SyntaxError {
+	// CHECK :Number of Parameters
+	if len(f.Parameters) != 3 {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] should have 3 parameter, found [%d]", fname, len(f.Parameters))}
+	}
+
+	// CHECK: Parameter 1 should be an identifier
+	pv1, ok := f.Parameters[0].(*ast.Value)
+	if !ok || pv1.V.Type != token.IDENT {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter 1 is not a Field Identifier", fname)}
+	}
+
+	// Field Names should always be Lower Case
+	pv1.V.Literal = strings.ToLower(pv1.V.Literal)
+
+	f1, e := c.verifyField(fname, pv1)
+	if e != nil {
+		return e
+	}
+
+	if f1 != nil && f1.Collection {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] is a Collection (use HAS, HAS_ANY or HAS_ALL)", fname, f1.Name)}
+	}
+
+	// CHECK: Parameter 2 and 3 (Range Bounds)
+	types := make([]token.TokenType, 2)
+	bounds := make([]*ast.Value, 2)
+	for i, pi := range f.Parameters[1:] {
+		pv, ok := pi.(*ast.Value)
+		if !ok {
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] invalid type for Parameter %d", fname, i+2)}
+		}
+
+		if pv.V.Type == token.IDENT {
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter %d should not be an Identifier (use @%s for a Field Reference)", fname, i+2, pv.V.Literal)}
+		}
+
+		bounds[i] = pv
+		types[i] = c.valueType(pv)
+
+		// Is Bound a Field Reference?
+		if pv.V.Type == token.FIELD { // YES: Use Field Type (if known)
+			pv.V.Literal = strings.ToLower(pv.V.Literal)
+
+			fb, e := c.verifyField(fname, pv)
+			if e != nil {
+				return e
+			}
+
+			if fb != nil && fb.Collection {
+				return &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] is a Collection and can't be used as a Field Reference", fname, fb.Name)}
+			}
+
+			types[i] = ""
+			if fb != nil {
+				types[i] = fb.Type
+			}
+		}
+
+		// Is Bound Compatible with Field?
+		if f1 != nil && types[i] != "" && !f1.Accepts(types[i]) { // NO
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] expects a value of type [%s] not [%s]", fname, f1.Name, f1.Type, types[i])}
+		}
+	}
+
+	// Do Bounds Share a Type?
+	if types[0] != "" && types[1] != "" && !sameValueType(types[0], types[1]) { // NO
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] bounds should share a type, found [%s] and [%s]", fname, types[0], types[1])}
+	}
+
+	// Are Both Bounds Literals?
+	if !isLiteral(bounds[0]) || !isLiteral(bounds[1]) { // NO: Can't Compare
+		return nil
+	}
+
+	// Is Low Bound <= High Bound?
+	if !lessOrEqual(bounds[0], bounds[1]) { // NO
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] low bound [%s] is greater than high bound [%s]", fname, bounds[0].ToString(), bounds[1].ToString())}
+	}
+
+	return nil
+}
+
+// in(field, value, ...)
+func (c *SyntaxChecker) verifyList(fname string, f *ast.Function) *SyntaxError {
+	// CHECK :Number of Parameters
+	if len(f.Parameters) < 2 {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] should have at least 2 parameter, found [%d]", fname, len(f.Parameters))}
+	}
+
+	// CHECK: Parameter 1 should be an identifier
+	pv1, ok := f.Parameters[0].(*ast.Value)
+	if !ok || pv1.V.Type != token.IDENT {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter 1 is not a Field Identifier", fname)}
+	}
+
+	// Field Names should always be Lower Case
+	pv1.V.Literal = strings.ToLower(pv1.V.Literal)
+
+	f1, e := c.verifyField(fname, pv1)
+	if e != nil {
+		return e
+	}
+
+	if f1 != nil && f1.Collection {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] is a Collection (use HAS_ANY)", fname, f1.Name)}
+	}
+
+	// CHECK: Remaining Parameters should be Values of the Same Type
+	var t token.TokenType
+	for i, pi := range f.Parameters[1:] {
+		pv, ok := pi.(*ast.Value)
+		if !ok || pv.V.Type == token.IDENT || pv.V.Type == token.FIELD {
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter %d should be a Value", fname, i+2)}
+		}
+
+		// Is Value Compatible with Field?
+		vt := c.valueType(pv)
+		if f1 != nil && !f1.Accepts(vt) { // NO
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] expects a value of type [%s] not [%s]", fname, f1.Name, f1.Type, vt)}
+		}
+
+		// Same Type as Previous Values?
+		if t != "" && !sameValueType(t, vt) { // NO
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter %d should be a [%s] not [%s]", fname, i+2, t, vt)}
+		}
+		t = vt
+	}
+
+	return nil
+}
+
+func (c *SyntaxChecker) verifyCollection(fname string, f *ast.Function) *SyntaxError {
+	// CHECK :Number of Parameters
+	if fname == "HAS" && len(f.Parameters) != 2 {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] should have 2 parameter, found [%d]", fname, len(f.Parameters))}
+	} else if len(f.Parameters) < 2 {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] should have at least 2 parameter, found [%d]", fname, len(f.Parameters))}
+	}
+
+	// CHECK: Parameter 1 should be an identifier
+	pv1, ok := f.Parameters[0].(*ast.Value)
+	if !ok || pv1.V.Type != token.IDENT {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter 1 is not a Field Identifier", fname)}
+	}
+
+	// Field Names should always be Lower Case
+	pv1.V.Literal = strings.ToLower(pv1.V.Literal)
+
+	f1, e := c.verifyField(fname, pv1)
+	if e != nil {
+		return e
+	}
+
+	if f1 != nil && !f1.Collection {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] is not a Collection", fname, f1.Name)}
+	}
+
+	// CHECK: Remaining Parameters should be Literal Values
+	for i, pi := range f.Parameters[1:] {
+		pv, ok := pi.(*ast.Value)
+		if !ok || pv.V.Type == token.IDENT || pv.V.Type == token.FIELD {
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter %d should be a Value", fname, i+2)}
+		}
+
+		// Is Value Compatible with Collection Elements?
+		if f1 != nil && !f1.Accepts(c.valueType(pv)) { // NO
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] expects a value of type [%s] not [%s]", fname, f1.Name, f1.Type, c.valueType(pv))}
+		}
+	}
+
+	return nil
+}
+
+func (c *SyntaxChecker) verifySearch(fname string, f *ast.Function) *SyntaxError {
+	// CHECK :Number of Parameters
+	if len(f.Parameters) < 2 {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] should have at least 2 parameter, found [%d]", fname, len(f.Parameters))}
+	}
+
+	// CHECK: All Parameters, except the Last, should be String Fields
+	last := len(f.Parameters) - 1
+	for i, pi := range f.Parameters[:last] {
+		pv, ok := pi.(*ast.Value)
+		if !ok || pv.V.Type != token.IDENT {
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter %d is not a Field Identifier", fname, i+1)}
+		}
+
+		// Field Names should always be Lower Case
+		pv.V.Literal = strings.ToLower(pv.V.Literal)
+
+		fi, e := c.verifyField(fname, pv)
+		if e != nil {
+			return e
+		}
+
+		if fi != nil && (fi.Collection || fi.Type != token.STRING) {
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] is not a String Field", fname, fi.Name)}
+		}
+	}
+
+	// CHECK: Last Parameter should be the Search Terms
+	pv, ok := f.Parameters[last].(*ast.Value)
+	if !ok || c.valueType(pv) != token.STRING {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter %d should be a String", fname, last+1)}
+	}
+
+	return nil
+}
+
+func (c *SyntaxChecker) verifyGeo(fname string, f *ast.Function) *SyntaxError {
+	// Parameters: NEAR(field, lat, lon, radius) or WITHIN_BOX(field, lat1, lon1, lat2, lon2)
+	count := 4
+	if fname == "WITHIN_BOX" {
+		count = 5
+	}
+
+	// CHECK :Number of Parameters
+	if len(f.Parameters) != count {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] should have %d parameter, found [%d]", fname, count, len(f.Parameters))}
+	}
+
+	// CHECK: Parameter 1 should be an identifier
+	pv1, ok := f.Parameters[0].(*ast.Value)
+	if !ok || pv1.V.Type != token.IDENT {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter 1 is not a Field Identifier", fname)}
+	}
+
+	// Field Names should always be Lower Case
+	pv1.V.Literal = strings.ToLower(pv1.V.Literal)
+
+	f1, e := c.verifyField(fname, pv1)
+	if e != nil {
+		return e
+	}
+
+	if f1 != nil && f1.Type != schema.POINT {
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] is not a Geographic Point", fname, f1.Name)}
+	}
+
+	// CHECK: Remaining Parameters should be Numbers
+	n := make([]float64, 0, count-1)
+	known := make([]bool, 0, count-1)
+	for i, pi := range f.Parameters[1:] {
+		pv, ok := pi.(*ast.Value)
+		if !ok || !isNumeric(c.valueType(pv)) {
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter %d should be a Number", fname, i+2)}
+		}
+
+		// NOTE: Bound Coordinates are Verified once the Filter is Bound
+		if !isLiteral(pv) {
+			n = append(n, 0)
+			known = append(known, false)
+			continue
+		}
+
+		v, _ := strconv.ParseFloat(pv.V.Literal, 64)
+		n = append(n, v)
+		known = append(known, true)
+	}
+
+	// CHECK: Coordinate Ranges (Latitude and Longitude Pairs)
+	for i := 0; i+1 < len(n) && i < 4; i += 2 {
+		if known[i] && (n[i] < -90 || n[i] > 90) {
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Latitude [%v] should be between -90 and 90", fname, n[i])}
+		}
+
+		if known[i+1] && (n[i+1] < -180 || n[i+1] > 180) {
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Longitude [%v] should be between -180 and 180", fname, n[i+1])}
+		}
+	}
+
+	if fname == "NEAR" {
+		// CHECK: Radius (in Meters)
+		if known[2] && n[2] <= 0 {
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Radius [%v] should be greater than 0", fname, n[2])}
+		}
+	} else if known[0] && known[2] && n[0] > n[2] {
+		// CHECK: 1st Corner should be South of 2nd Corner
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] Latitude [%v] is north of Latitude [%v]", fname, n[0], n[2])}
+	}
+
+	return nil
+}
+
+func (c *SyntaxChecker) verifyLimits(fname string, f *ast.Function) *SyntaxError {
+	// NOTE: Nesting is Always Bounded (ASTs can be Built without the Parser)
+	if max := limits.MaxDepth(c.Limits); limits.Exceeds(c.depth, max) {
+		return &SyntaxError{Code: limits.ERR_DEPTH, Message: fmt.Sprintf("Function [%s] exceeds maximum nesting [%d]", fname, max)}
+	}
+
+	if c.Limits == nil {
+		return nil
+	}
+
+	switch fname {
+	case "CONTAINS", "ICONTAINS", "STARTSWITH", "ENDSWITH", "MATCHES", "SEARCH":
+		c.patterns++
+		if limits.Exceeds(c.patterns, c.Limits.MaxPatterns) {
+			return &SyntaxError{Code: limits.ERR_PATTERNS, Message: fmt.Sprintf("Filter exceeds maximum number of pattern operators [%d]", c.Limits.MaxPatterns)}
+		}
+	case "IN", "HAS_ANY", "HAS_ALL":
+		if limits.Exceeds(len(f.Parameters)-1, c.Limits.MaxListSize) {
+			return &SyntaxError{Code: limits.ERR_LIST_SIZE, Message: fmt.Sprintf("Function [%s] exceeds maximum list size [%d]", fname, c.Limits.MaxListSize)}
+		}
+	}
+
+	for _, pi := range f.Parameters {
+		pv, ok := pi.(*ast.Value)
+		if !ok || pv.V.Type != token.STRING {
+			continue
+		}
+
+		if limits.Exceeds(len([]rune(pv.V.Literal)), c.Limits.MaxStringLength) {
+			return &SyntaxError{Code: limits.ERR_STRING_LENGTH, Message: fmt.Sprintf("Function [%s] string exceeds maximum length [%d]", fname, c.Limits.MaxStringLength)}
+		}
+
+		c.wildcards += strings.Count(pv.V.Literal, "\uFFFD")
+		if limits.Exceeds(c.wildcards, c.Limits.MaxWildcards) {
+			return &SyntaxError{Code: limits.ERR_WILDCARDS, Message: fmt.Sprintf("Filter exceeds maximum number of wildcards [%d]", c.Limits.MaxWildcards)}
+		}
+	}
+
+	return nil
+}
+
+func (c *SyntaxChecker) authorizeOperator(fname string, f *ast.Function) *SyntaxError {
+	// Split Parameters into Fields and Values
+	fields := make([]string, 0)
+	values := make([]*ast.Value, 0)
+	for _, pi := range f.Parameters {
+		pv := pi.(*ast.Value)
+		if pv.V.Type == token.IDENT || pv.V.Type == token.FIELD {
+			fields = append(fields, pv.V.Literal)
+		} else {
+			values = append(values, pv)
+		}
+	}
+
+	for _, field := range fields {
+		// Is Operator in Field's Allowlist?
+		if c.Schema != nil {
+			if sf := c.Schema.Field(field); sf != nil && !sf.Allows(fname) { // NO
+				return &SyntaxError{Message: fmt.Sprintf("Function [%s] is not allowed on Field [%s]", fname, field)}
+			}
+		}
+
+		if e := c.authorizeField(fname, field, values...); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// Call Authorization Callback (once per value)
+func (c *SyntaxChecker) authorizeField(operator string, field string, values ...*ast.Value) *SyntaxError {
+	if c.Authorize == nil {
+		return nil
+	}
+
+	if len(values) == 0 {
+		values = []*ast.Value{nil}
+	}
+
+	for _, v := range values {
+		if !c.Authorize(field, operator, v, c.Context) {
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] on Field [%s] is not authorized", operator, field)}
+		}
+	}
+
+	return nil
+}
+
+func (c *SyntaxChecker) verifyParams(fname string, f *ast.Function) *SyntaxError {
+	for _, pi := range f.Parameters {
+		pv, ok := pi.(*ast.Value)
+		if !ok || pv.V.Type != token.PARAM {
+			continue
+		}
+
+		if _, ok := c.Params[pv.V.Literal]; !ok {
+			return &SyntaxError{Message: fmt.Sprintf("Function [%s] Parameter [$%s] is not declared", fname, pv.V.Literal)}
+		}
+	}
+
+	return nil
+}
+
+// Value Type (Declared Type for Bind Parameters)
+func (c *SyntaxChecker) valueType(v *ast.Value) token.TokenType {
+	if v.V.Type == token.PARAM {
+		return c.Params[v.V.Literal]
+	}
+	return v.V.Type
+}
+
+func (c *SyntaxChecker) verifyField(fname string, v *ast.Value) (*schema.Field, *SyntaxError) {
+	// Have a Schema to Verify Against?
+	if c.Schema == nil { // NO: Accept any Field
+		return nil, nil
+	}
+
+	f := c.Schema.Field(v.V.Literal)
+	if f == nil {
+		return nil, &SyntaxError{Message: fmt.Sprintf("Function [%s] Field [%s] is not recognized", fname, v.V.Literal)}
+	}
+	return f, nil
+}
+
+func (c *SyntaxChecker) verifyPattern(fname string, v *ast.Value) *SyntaxError {
+	pattern := v.Pattern()
+
+	// Limits Replace the (Deprecated) Checker Bound
+	max := c.MaxPatternLength
+	if c.Limits != nil {
+		max = c.Limits.MaxPatternLength
+	}
+
+	// Pattern too Long?
+	if limits.Exceeds(len([]rune(pattern)), max) { // YES
+		return &SyntaxError{Code: limits.ERR_PATTERN_LENGTH, Message: fmt.Sprintf("Function [%s] Pattern exceeds maximum length [%d]", fname, max)}
+	}
+
+	// Valid RE2 Regular Expression?
+	if _, err := regexp.Compile(pattern); err != nil { // NO
+		return &SyntaxError{Message: fmt.Sprintf("Function [%s] Invalid Pattern [%s]", fname, err.Error())}
+	}
+
+	return nil
+}
+
+// Known Functions (Upper Case Names) and their Class, in Display Order
+var functionTable = []struct {
+	name      string
+	class     string
+	signature string // Completion and Hover Detail
+}{
+	{"AND", "logical-binary", "and(filter, filter)"},
+	{"OR", "logical-binary", "or(filter, filter)"},
+	{"NOT", "logical-unary", "not(filter)"},
+	{"EQ", "operator", "eq(field, value)"},
+	{"NEQ", "operator", "neq(field, value)"},
+	{"GT", "operator", "gt(field, value)"},
+	{"GTE", "operator", "gte(field, value)"},
+	{"LT", "operator", "lt(field, value)"},
+	{"LTE", "operator", "lte(field, value)"},
+	{"BETWEEN", "operator-range", "between(field, low, high)"},
+	{"IN", "operator-list", "in(field, value, ...)"},
+	{"CONTAINS", "operator", "contains(field, pattern)"},
+	{"STARTSWITH", "operator", "startswith(field, prefix)"},
+	{"ENDSWITH", "operator", "endswith(field, suffix)"},
+	{"ICONTAINS", "operator", "icontains(field, pattern)"},
+	{"IEQ", "operator", "ieq(field, value)"},
+	{"MATCHES", "operator", "matches(field, regexp)"},
+	{"EXISTS", "operator-unary", "exists(field)"},
+	{"MISSING", "operator-unary", "missing(field)"},
+	{"HAS", "operator-collection", "has(field, value)"},
+	{"HAS_ANY", "operator-collection", "has_any(field, value, ...)"},
+	{"HAS_ALL", "operator-collection", "has_all(field, value, ...)"},
+	{"SEARCH", "operator-search", "search(field, ..., terms)"},
+	{"NEAR", "operator-geo", "near(field, lat, lon, radius)"},
+	{"WITHIN_BOX", "operator-geo", "within_box(field, lat1, lon1, lat2, lon2)"},
+	{"MACRO", "macro", "macro(name, value, ...)"},
+}
+
+// Known Functions (Upper Case Names)
+var FUNCTIONS = functionNames()
+
+// Function Name to Class
+var functionClasses = functionClassMap()
+
+// Function Name to Signature
+var functionSignatures = functionSignatureMap()
+
+func functionNames() []string {
+	l := make([]string, 0, len(functionTable))
+	for _, f := range functionTable {
+		l = append(l, f.name)
+	}
+	return l
+}
+
+func functionClassMap() map[string]string {
+	m := make(map[string]string, len(functionTable))
+	for _, f := range functionTable {
+		m[f.name] = f.class
+	}
+	return m
+}
+
+func functionSignatureMap() map[string]string {
+	m := make(map[string]string, len(functionTable))
+	for _, f := range functionTable {
+		m[f.name] = f.signature
+	}
+	return m
+}
+
+// Function Signature (i.e. "eq(field, value)", "" - Unknown Function)
+func FunctionSignature(name string) string {
+	return functionSignatures[strings.ToUpper(name)]
+}
+
+// Function Class (i.e. "logical-binary", "operator-range", "unknown")
+func FunctionClass(name string) string {
+	return functionType(strings.ToUpper(name))
+}
+
 func functionType(name string) string {
-	switch name {
-	case "NOT":
-		return "logical-unary"
-	case "OR", "AND":
-		return "logical-binary"
-	case "EQ", "NEQ", "GT", "GTE", "LT", "LTE", "CONTAINS", "IN":
-		return "operator"
+	if class, ok := functionClasses[name]; ok {
+		return class
 	}
 	return "unknown"
 }
+
+func isLiteral(v *ast.Value) bool {
+	return v.V.Type == token.STRING || isNumeric(v.V.Type)
+}
+
+func isNumeric(t token.TokenType) bool {
+	return t == token.INT || t == token.NUMBER
+}
+
+func sameValueType(t1 token.TokenType, t2 token.TokenType) bool {
+	// Any Number can be Compared to another Number
+	if isNumeric(t1) && isNumeric(t2) {
+		return true
+	}
+	return t1 == t2
+}
+
+func lessOrEqual(v1 *ast.Value, v2 *ast.Value) bool {
+	// Numeric Values?
+	if isNumeric(v1.V.Type) { // YES: Compare as Numbers
+		n1, _ := strconv.ParseFloat(v1.V.Literal, 64)
+		n2, _ := strconv.ParseFloat(v2.V.Literal, 64)
+		return n1 <= n2
+	}
+
+	return v1.V.Literal <= v2.V.Literal
+}
@@ -0,0 +1,488 @@
+package syntax
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/builder"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/limits"
+	"github.com/objectvault/filter-parser/parser"
+	"github.com/objectvault/filter-parser/schema"
+	"github.com/objectvault/filter-parser/token"
+)
+
+// Schema Shared by Tests
+func testSchema() *schema.Schema {
+	s := schema.NewSchema()
+	s.AddField("a", token.STRING)
+	s.AddField("b", token.STRING)
+	s.AddField("n", token.INT)
+	s.AddField("x", token.NUMBER)
+	s.AddCollection("tags", token.STRING)
+	s.AddCollection("scores", token.INT)
+	s.AddField("loc", schema.POINT)
+	return s
+}
+
+// Parse Input (Fails Test if Input is not a Valid Filter)
+func parseFilter(t *testing.T, input string) *ast.Filter {
+	r := parser.NewParser(lexer.NewLexer(input)).ParseFilter()
+
+	f, ok := r.(*ast.Filter)
+	if !ok {
+		t.Fatalf("parse [%s] failed. got=%T (%+v)", input, r, r)
+	}
+	return f
+}
+
+// Verify Input against Schema (returns "" if Valid)
+func verifyFilter(t *testing.T, s *schema.Schema, input string) string {
+	c := NewSyntaxChecker(parseFilter(t, input))
+	c.Schema = s
+
+	if e := c.Verify(); e != nil {
+		return e.Message
+	}
+	return ""
+}
+
+func TestFieldReferences(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`eq(a, @b)`, ""},
+		{`neq(A, @B)`, ""},
+		{`gt(n, @x)`, ""},
+		{`ieq(a, @b)`, ""},
+		{`eq(a, @n)`, "Function [EQ] Field [a] of type [STRING] can't be compared to Field [n] of type [INT]"},
+		{`eq(a, @zz)`, "Function [EQ] Field [zz] is not recognized"},
+		{`eq(a, b)`, "Function [EQ] Parameter 2 should not be an Identifier (use @b for a Field Reference)"},
+		{`contains(a, @b)`, "Function [CONTAINS] Parameter 2 should be a String no [FIELD]"},
+	}
+
+	for i, tt := range tests {
+		got := verifyFilter(t, testSchema(), tt.input)
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestStringOperators(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`startswith(a, "abc")`, ""},
+		{`endswith(a, "abc")`, ""},
+		{`icontains(a, "*abc*")`, ""},
+		{`ieq(a, "ABC")`, ""},
+		{`startswith(a, 1)`, "Function [STARTSWITH] Parameter 2 should be a String no [INT]"},
+		{`endswith(a, 1.5)`, "Function [ENDSWITH] Parameter 2 should be a String no [NUMBER]"},
+		{`icontains(n, "1")`, "Function [ICONTAINS] Field [n] expects a value of type [INT] not [STRING]"},
+		{`ieq(a, 1)`, "Function [IEQ] Parameter 2 should be a String or Field Reference no [INT]"},
+	}
+
+	for i, tt := range tests {
+		got := verifyFilter(t, testSchema(), tt.input)
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`matches(a, "^a.*$")`, ""},
+		{`matches(a, "^[a-z]+\*$")`, ""},
+		{`matches(a, "(")`, "Function [MATCHES] Invalid Pattern [error parsing regexp: missing closing ): `(`]"},
+		{`matches(a, 1)`, "Function [MATCHES] Parameter 2 should be a String no [INT]"},
+		{`matches(n, "1")`, "Function [MATCHES] Field [n] expects a value of type [INT] not [STRING]"},
+	}
+
+	for i, tt := range tests {
+		got := verifyFilter(t, testSchema(), tt.input)
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`between(n, 1, 10)`, ""},
+		{`between(n, 1, 2.5)`, ""},
+		{`between(n, 5, 5)`, ""},
+		{`between(a, "a", "m")`, ""},
+		{`between(n, @x, 10)`, ""},
+		{`between(n, 1)`, "Function [BETWEEN] should have 3 parameter, found [2]"},
+		{`between(n, 10, 1)`, "Function [BETWEEN] low bound [10] is greater than high bound [1]"},
+		{`between(a, "z", "a")`, "Function [BETWEEN] low bound [\"z\"] is greater than high bound [\"a\"]"},
+		{`between(n, "a", 1)`, "Function [BETWEEN] Field [n] expects a value of type [INT] not [STRING]"},
+		{`between(n, @a, 1)`, "Function [BETWEEN] Field [n] expects a value of type [INT] not [STRING]"},
+		{`between(a, b, "z")`, "Function [BETWEEN] Parameter 2 should not be an Identifier (use @b for a Field Reference)"},
+		{`between(zz, 1, 2)`, "Function [BETWEEN] Field [zz] is not recognized"},
+	}
+
+	for i, tt := range tests {
+		got := verifyFilter(t, testSchema(), tt.input)
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestPresence(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`exists(a)`, ""},
+		{`missing(N)`, ""},
+		{`exists(a, b)`, "Function [EXISTS] should have 1 parameter, found [2]"},
+		{`exists(zz)`, "Function [EXISTS] Field [zz] is not recognized"},
+	}
+
+	for i, tt := range tests {
+		got := verifyFilter(t, testSchema(), tt.input)
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestCollections(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`has(tags, "a")`, ""},
+		{`has_any(tags, "a", "b")`, ""},
+		{`has_all(scores, 1, 2, 3)`, ""},
+		{`has(tags, "a", "b")`, "Function [HAS] should have 2 parameter, found [3]"},
+		{`has_any(tags)`, "Function [HAS_ANY] should have at least 2 parameter, found [1]"},
+		{`has(a, "a")`, "Function [HAS] Field [a] is not a Collection"},
+		{`has_any(scores, 1, "b")`, "Function [HAS_ANY] Field [scores] expects a value of type [INT] not [STRING]"},
+		{`has(tags, @a)`, "Function [HAS] Parameter 2 should be a Value"},
+		{`eq(tags, "a")`, "Function [EQ] Field [tags] is a Collection (use HAS, HAS_ANY or HAS_ALL)"},
+		{`eq(a, @tags)`, "Function [EQ] Field [tags] is a Collection and can't be used as a Field Reference"},
+		{`ieq(a, @tags)`, "Function [IEQ] Field [tags] is a Collection and can't be used as a Field Reference"},
+		{`between(n, @scores, 10)`, "Function [BETWEEN] Field [scores] is a Collection and can't be used as a Field Reference"},
+	}
+
+	for i, tt := range tests {
+		got := verifyFilter(t, testSchema(), tt.input)
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestIN(t *testing.T) {
+	tests := []struct {
+		input         string
+		schema        bool
+		expectedError string
+	}{
+		{`in(a, "x")`, true, ""},
+		{`in(a, "x", "y", "z")`, true, ""},
+		{`in(x, 1, 2.5)`, true, ""},
+		{`in(a)`, true, "Function [IN] should have at least 2 parameter, found [1]"},
+		{`in(a, "x", 1)`, true, "Function [IN] Field [a] expects a value of type [STRING] not [INT]"},
+		{`in(tags, "x")`, true, "Function [IN] Field [tags] is a Collection (use HAS_ANY)"},
+		{`in(a, @b)`, true, "Function [IN] Parameter 2 should be a Value"},
+		{`in(zz, 1)`, true, "Function [IN] Field [zz] is not recognized"},
+		{`in(zz, 1, "x")`, false, "Function [IN] Parameter 3 should be a [INT] not [STRING]"},
+	}
+
+	for i, tt := range tests {
+		var s *schema.Schema
+		if tt.schema {
+			s = testSchema()
+		}
+
+		got := verifyFilter(t, s, tt.input)
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`search(a, "foo")`, ""},
+		{`search(a, b, "foo bar*")`, ""},
+		{`search(a)`, "Function [SEARCH] should have at least 2 parameter, found [1]"},
+		{`search(n, "foo")`, "Function [SEARCH] Field [n] is not a String Field"},
+		{`search(a, tags, "foo")`, "Function [SEARCH] Field [tags] is not a String Field"},
+		{`search(a, 1)`, "Function [SEARCH] Parameter 2 should be a String"},
+		{`search(a, zz, "foo")`, "Function [SEARCH] Field [zz] is not recognized"},
+	}
+
+	for i, tt := range tests {
+		got := verifyFilter(t, testSchema(), tt.input)
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestGeo(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`near(loc, 40.7, -74.0, 500)`, ""},
+		{`within_box(loc, 10, -10, 20, 10)`, ""},
+		{`within_box(loc, 10, 170, 20, -170)`, ""},
+		{`near(loc, 40.7, -74.0)`, "Function [NEAR] should have 4 parameter, found [3]"},
+		{`within_box(loc, 1, 2, 3)`, "Function [WITHIN_BOX] should have 5 parameter, found [4]"},
+		{`near(a, 0, 0, 1)`, "Function [NEAR] Field [a] is not a Geographic Point"},
+		{`near(loc, "0", 0, 1)`, "Function [NEAR] Parameter 2 should be a Number"},
+		{`near(loc, 91, 0, 1)`, "Function [NEAR] Latitude [91] should be between -90 and 90"},
+		{`near(loc, 0, -181, 1)`, "Function [NEAR] Longitude [-181] should be between -180 and 180"},
+		{`near(loc, 0, 0, 0)`, "Function [NEAR] Radius [0] should be greater than 0"},
+		{`within_box(loc, 20, 0, 10, 1)`, "Function [WITHIN_BOX] Latitude [20] is north of Latitude [10]"},
+		{`eq(loc, 1)`, "Function [EQ] Field [loc] expects a value of type [POINT] not [INT]"},
+	}
+
+	for i, tt := range tests {
+		got := verifyFilter(t, testSchema(), tt.input)
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+// Literal Coordinates are Checked even when Mixed with Bind Parameters
+func TestGeoParams(t *testing.T) {
+	params := map[string]token.TokenType{"lat": token.NUMBER, "lon": token.NUMBER, "r": token.INT}
+
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`near(loc, $lat, $lon, $r)`, ""},
+		{`within_box(loc, $lat, $lon, 10, 1)`, ""},
+		{`near(loc, 100, 500, $r)`, "Function [NEAR] Latitude [100] should be between -90 and 90"},
+		{`near(loc, $lat, 500, 1)`, "Function [NEAR] Longitude [500] should be between -180 and 180"},
+		{`near(loc, $lat, "abc", 5)`, "Function [NEAR] Parameter 3 should be a Number"},
+		{`near(loc, $lat, $lon, -5)`, "Function [NEAR] Radius [-5] should be greater than 0"},
+		{`within_box(loc, $lat, 1, "x", 2)`, "Function [WITHIN_BOX] Parameter 4 should be a Number"},
+		{`within_box(loc, 20, $lon, 10, $lon)`, "Function [WITHIN_BOX] Latitude [20] is north of Latitude [10]"},
+	}
+
+	for i, tt := range tests {
+		c := NewSyntaxChecker(parseFilter(t, tt.input))
+		c.Schema = testSchema()
+		c.Params = params
+
+		got := ""
+		if e := c.Verify(); e != nil {
+			got = e.Message
+		}
+
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestParams(t *testing.T) {
+	params := map[string]token.TokenType{"name": token.STRING, "1": token.INT, "re": token.STRING}
+
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`eq(a, $name)`, ""},
+		{`gt(x, $1)`, ""},
+		{`between(n, $1, 10)`, ""},
+		{`matches(a, $re)`, ""},
+		{`eq(a, $zz)`, "Function [EQ] Parameter [$zz] is not declared"},
+		{`eq(a, $1)`, "Function [EQ] Field [a] expects a value of type [STRING] not [INT]"},
+		{`contains(a, $1)`, "Function [CONTAINS] Parameter 2 should be a String no [INT]"},
+		{`between(n, $name, 10)`, "Function [BETWEEN] Field [n] expects a value of type [INT] not [STRING]"},
+	}
+
+	for i, tt := range tests {
+		c := NewSyntaxChecker(parseFilter(t, tt.input))
+		c.Schema = testSchema()
+		c.Params = params
+
+		got := ""
+		if e := c.Verify(); e != nil {
+			got = e.Message
+		}
+
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input         string
+		limits        *limits.Limits
+		expectedCode  string
+		expectedError string
+	}{
+		{`not(not(eq(a, "x")))`, nil, "", ""},
+		{`not(not(eq(a, "x")))`, &limits.Limits{MaxDepth: 3}, "", ""},
+		{`not(not(eq(a, "x")))`, &limits.Limits{MaxDepth: 2}, limits.ERR_DEPTH, "Function [EQ] exceeds maximum nesting [2]"},
+		{`eq(a, "abc")`, &limits.Limits{MaxStringLength: 3}, "", ""},
+		{`eq(a, "abcd")`, &limits.Limits{MaxStringLength: 3}, limits.ERR_STRING_LENGTH, "Function [EQ] string exceeds maximum length [3]"},
+		{`and(contains(a, "x"), eq(b, "y"))`, &limits.Limits{MaxPatterns: 1}, "", ""},
+		{`and(contains(a, "x"), startswith(b, "y"))`, &limits.Limits{MaxPatterns: 1}, limits.ERR_PATTERNS, "Filter exceeds maximum number of pattern operators [1]"},
+		{`matches(a, "^a.c")`, &limits.Limits{MaxPatternLength: 4}, "", ""},
+		{`matches(a, "^abc$")`, &limits.Limits{MaxPatternLength: 4}, limits.ERR_PATTERN_LENGTH, "Function [MATCHES] Pattern exceeds maximum length [4]"},
+		{`and(contains(a, "*x*"), contains(b, "y"))`, &limits.Limits{MaxWildcards: 2}, "", ""},
+		{`and(contains(a, "*x*"), contains(b, "*y"))`, &limits.Limits{MaxWildcards: 2}, limits.ERR_WILDCARDS, "Filter exceeds maximum number of wildcards [2]"},
+		{`has_any(tags, "a", "b")`, &limits.Limits{MaxListSize: 2}, "", ""},
+		{`has_any(tags, "a", "b", "c")`, &limits.Limits{MaxListSize: 2}, limits.ERR_LIST_SIZE, "Function [HAS_ANY] exceeds maximum list size [2]"},
+		{`has_all(tags, "a", "b", "c")`, &limits.Limits{MaxListSize: 2}, limits.ERR_LIST_SIZE, "Function [HAS_ALL] exceeds maximum list size [2]"},
+		// HAVING is Counted with the Rest of the Query
+		{`fields(a, max(b)) group(a) contains(b, "x") having(contains(max_b, "y"))`, &limits.Limits{MaxPatterns: 1}, limits.ERR_PATTERNS, "Having Filter exceeds maximum number of pattern operators [1]"},
+		{`fields(a, max(b)) group(a) contains(b, "*x*") having(contains(max_b, "*y"))`, &limits.Limits{MaxWildcards: 2}, limits.ERR_WILDCARDS, "Having Filter exceeds maximum number of wildcards [2]"},
+		{`fields(a, max(b)) group(a) having(not(not(eq(max_b, "y"))))`, &limits.Limits{MaxDepth: 2}, limits.ERR_DEPTH, "Having Function [EQ] exceeds maximum nesting [2]"},
+	}
+
+	for i, tt := range tests {
+		c := NewSyntaxChecker(parseQuery(t, tt.input))
+		c.Schema = testSchema()
+		c.Limits = tt.limits
+
+		code, got := "", ""
+		if e := c.Verify(); e != nil {
+			code, got = e.Code, e.Message
+		}
+
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+
+		if code != tt.expectedCode {
+			t.Fatalf("tests[%d] - code wrong. expected=%q, got=%q", i, tt.expectedCode, code)
+		}
+	}
+
+	// Limits are Opt-In
+	if c := NewSyntaxChecker(nil); c.Limits != nil {
+		t.Fatalf("default limits wrong. expected=nil, got=%+v", c.Limits)
+	}
+}
+
+// Nesting and Pattern Length are Bounded even without Limits
+func TestDefaultBounds(t *testing.T) {
+	// Built AST (No Parser Limits Applied)
+	deep := builder.ASTEQ("a", builder.ASTLiteral("x"))
+	for i := 1; i <= limits.STACK_MAX_DEPTH; i++ {
+		deep = builder.ASTNOT(deep, nil)
+	}
+
+	long := `matches(a, "` + strings.Repeat("a", DEFAULT_MAX_PATTERN_LENGTH+1) + `")`
+
+	tests := []struct {
+		filter        *ast.Filter
+		limits        *limits.Limits
+		maxPattern    int
+		expectedError string
+	}{
+		{builder.ASTFilter(deep), nil, DEFAULT_MAX_PATTERN_LENGTH, "Function [EQ] exceeds maximum nesting [128]"},
+		{builder.ASTFilter(deep.Parameters[0].(*ast.Function)), nil, DEFAULT_MAX_PATTERN_LENGTH, ""},
+		{parseFilter(t, long), nil, DEFAULT_MAX_PATTERN_LENGTH, "Function [MATCHES] Pattern exceeds maximum length [256]"},
+		{parseFilter(t, long), nil, 0, ""},
+		{parseFilter(t, long), &limits.Limits{}, DEFAULT_MAX_PATTERN_LENGTH, ""},
+		{parseFilter(t, long), &limits.Limits{MaxPatternLength: 10}, 0, "Function [MATCHES] Pattern exceeds maximum length [10]"},
+	}
+
+	for i, tt := range tests {
+		c := NewSyntaxChecker(tt.filter)
+		c.Schema = testSchema()
+		c.Limits = tt.limits
+		c.MaxPatternLength = tt.maxPattern
+
+		got := ""
+		if e := c.Verify(); e != nil {
+			got = e.Message
+		}
+
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+
+	if c := NewSyntaxChecker(nil); c.MaxPatternLength != DEFAULT_MAX_PATTERN_LENGTH {
+		t.Fatalf("default pattern length wrong. expected=%d, got=%d", DEFAULT_MAX_PATTERN_LENGTH, c.MaxPatternLength)
+	}
+}
+
+func TestErrorNode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Function that Failed ("" - No Error)
+	}{
+		{`eq(a, "x")`, ""},
+		{`eq(zz, "x")`, "EQ ( zz, \"x\" )"},
+		{`and(eq(a, "x"), not(gt(n, "y")))`, "GT ( n, \"y\" )"},
+		{`and(eq(a, "x"))`, "AND ( eq ( a, \"x\" ) )"},
+		{`or(eq(a, "x"), unknown(a))`, "UNKNOWN ( a )"},
+	}
+
+	for i, tt := range tests {
+		c := NewSyntaxChecker(parseFilter(t, tt.input))
+		c.Schema = testSchema()
+
+		got := ""
+		if e := c.Verify(); e != nil {
+			if e.Node == nil {
+				t.Fatalf("tests[%d] - error [%s] has no node", i, e.Message)
+			}
+			got = e.Node.ToString()
+		}
+
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - node wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestFunctionTable(t *testing.T) {
+	for _, name := range FUNCTIONS {
+		if FunctionClass(name) == "unknown" {
+			t.Fatalf("function [%s] has no class", name)
+		}
+
+		// Signature Starts with the Function Name
+		if s := FunctionSignature(strings.ToLower(name)); !strings.HasPrefix(s, strings.ToLower(name)+"(") {
+			t.Fatalf("function [%s] signature wrong. got=%q", name, s)
+		}
+	}
+
+	if FunctionClass("zz") != "unknown" || FunctionSignature("zz") != "" {
+		t.Fatalf("unknown function found")
+	}
+}
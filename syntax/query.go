@@ -0,0 +1,171 @@
+package syntax
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/schema"
+)
+
+func (c *SyntaxChecker) verifyQuery(q *ast.Query) *SyntaxError {
+	// Have Fields Clause?
+	if q.Fields != nil { // YES: Verify it
+		if e := c.verifyProjection(q.Fields); e != nil {
+			return e
+		}
+	}
+
+	// Have Filter Clause?
+	if q.Filter != nil { // YES: Verify it
+		if e := c.verifyFilter(q.Filter); e != nil {
+			return e
+		}
+	}
+
+	// Is Aggregation Query?
+	var aliases map[string]*schema.Field
+	if q.Group != nil || q.Having != nil || (q.Fields != nil && len(q.Fields.Aggregates) > 0) { // YES: Verify Group and Having
+		var e *SyntaxError
+		aliases, e = c.verifyAggregation(q)
+		if e != nil {
+			return e
+		}
+	}
+
+	// Have Sort Clause?
+	if q.Sort != nil { // YES: Verify it
+		if e := c.verifySort(q.Sort, aliases); e != nil {
+			return e
+		}
+	}
+
+	// Have Limit Clause?
+	if q.Limit != nil { // YES: Verify it
+		return c.verifyLimit(q.Limit)
+	}
+
+	return nil
+}
+
+func (c *SyntaxChecker) verifyProjection(fl *ast.Fields) *SyntaxError {
+	if len(fl.Fields)+len(fl.Aggregates) == 0 {
+		return &SyntaxError{Message: "Fields should have at least 1 field"}
+	}
+
+	seen := make(map[string]bool)
+	for i := range fl.Fields {
+		f := &fl.Fields[i]
+
+		// Field Names should always be Lower Case
+		f.Literal = strings.ToLower(f.Literal)
+
+		if seen[f.Literal] {
+			return &SyntaxError{Message: fmt.Sprintf("Fields Field [%s] is repeated", f.Literal)}
+		}
+		seen[f.Literal] = true
+
+		// Have a Schema to Verify Against?
+		if c.Schema != nil && c.Schema.Field(f.Literal) == nil { // YES: Field should Exist
+			return &SyntaxError{Message: fmt.Sprintf("Fields Field [%s] is not recognized", f.Literal)}
+		}
+
+		if e := c.authorizeField("FIELDS", f.Literal); e != nil {
+			return e
+		}
+	}
+
+	for _, a := range fl.Aggregates {
+		if e := c.verifyAggregate(a); e != nil {
+			return e
+		}
+
+		if seen[a.Alias()] {
+			return &SyntaxError{Message: fmt.Sprintf("Fields Aggregate [%s] is repeated", a.ToString())}
+		}
+		seen[a.Alias()] = true
+	}
+
+	return nil
+}
+
+// NOTE: aliases is nil unless this is an Aggregation Query
+func (c *SyntaxChecker) verifySort(s *ast.Sort, aliases map[string]*schema.Field) *SyntaxError {
+	if len(s.Fields) == 0 {
+		return &SyntaxError{Message: "Sort should have at least 1 field"}
+	}
+
+	seen := make(map[string]bool)
+	for _, sf := range s.Fields {
+		// Field Names should always be Lower Case
+		sf.Field.Literal = strings.ToLower(sf.Field.Literal)
+
+		if seen[sf.Field.Literal] {
+			return &SyntaxError{Message: fmt.Sprintf("Sort Field [%s] is repeated", sf.Field.Literal)}
+		}
+		seen[sf.Field.Literal] = true
+
+		// Is Aggregation Query?
+		if aliases != nil { // YES: Can only Sort on Group Fields and Aggregates
+			if _, ok := aliases[sf.Field.Literal]; !ok {
+				return &SyntaxError{Message: fmt.Sprintf("Sort Field [%s] is not a group field or aggregate", sf.Field.Literal)}
+			}
+			continue
+		}
+
+		// Have a Schema to Verify Against?
+		if c.Schema == nil { // NO: Accept any Field
+			continue
+		}
+
+		f := c.Schema.Field(sf.Field.Literal)
+		if f == nil {
+			return &SyntaxError{Message: fmt.Sprintf("Sort Field [%s] is not recognized", sf.Field.Literal)}
+		}
+
+		if f.Collection || f.Type == schema.POINT {
+			return &SyntaxError{Message: fmt.Sprintf("Sort Field [%s] is not sortable", sf.Field.Literal)}
+		}
+	}
+
+	// Is Aggregation Query?
+	if aliases != nil { // YES: Group Fields and Aggregates have already been Authorized
+		return nil
+	}
+
+	// Can the Caller Sort on the Fields?
+	for _, sf := range s.Fields {
+		if e := c.authorizeField("SORT", sf.Field.Literal); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+func (c *SyntaxChecker) verifyLimit(l *ast.Limit) *SyntaxError {
+	// Row Count should be Positive
+	if n, err := strconv.Atoi(l.Count.Literal); err != nil || n <= 0 {
+		return &SyntaxError{Message: fmt.Sprintf("Limit row count [%s] should be greater than 0", l.Count.Literal)}
+	}
+
+	// Offset (if any) should not be Negative
+	if l.Offset != nil {
+		if n, err := strconv.Atoi(l.Offset.Literal); err != nil || n < 0 {
+			return &SyntaxError{Message: fmt.Sprintf("Limit offset [%s] should not be negative", l.Offset.Literal)}
+		}
+	}
+
+	return nil
+}
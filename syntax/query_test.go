@@ -0,0 +1,189 @@
+package syntax
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+	"github.com/objectvault/filter-parser/schema"
+)
+
+// Parse Query (Fails Test if Input is not a Valid Query)
+func parseQuery(t *testing.T, input string) *ast.Query {
+	r := parser.NewParser(lexer.NewLexer(input)).ParseQuery()
+
+	q, ok := r.(*ast.Query)
+	if !ok {
+		t.Fatalf("parse [%s] failed. got=%T (%+v)", input, r, r)
+	}
+	return q
+}
+
+// Verify Query against Schema (returns "" if Valid)
+func verifyQuery(t *testing.T, s *schema.Schema, input string) string {
+	c := NewSyntaxChecker(parseQuery(t, input))
+	c.Schema = s
+
+	if e := c.Verify(); e != nil {
+		return e.Message
+	}
+	return ""
+}
+
+func TestSortAndLimit(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`sort(a desc, n) limit(10)`, ""},
+		{`eq(a, "x") sort(A) limit(10, offset 0)`, ""},
+		{`sort(a, A)`, "Sort Field [a] is repeated"},
+		{`sort(zz)`, "Sort Field [zz] is not recognized"},
+		{`sort(tags)`, "Sort Field [tags] is not sortable"},
+		{`sort(loc)`, "Sort Field [loc] is not sortable"},
+		{`limit(0)`, "Limit row count [0] should be greater than 0"},
+		{`limit(-5)`, "Limit row count [-5] should be greater than 0"},
+		{`limit(1, offset -1)`, "Limit offset [-1] should not be negative"},
+		{`eq(zz, 1) sort(a)`, "Function [EQ] Field [zz] is not recognized"},
+	}
+
+	for i, tt := range tests {
+		got := verifyQuery(t, testSchema(), tt.input)
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestProjection(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`fields(a, n)`, ""},
+		{`fields(A, tags) eq(a, "x")`, ""},
+		{`fields(a, A)`, "Fields Field [a] is repeated"},
+		{`fields(zz)`, "Fields Field [zz] is not recognized"},
+	}
+
+	for i, tt := range tests {
+		got := verifyQuery(t, testSchema(), tt.input)
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestAggregation(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`fields(a, count()) group(a)`, ""},
+		{`fields(a, sum(n), avg(x), min(b), max(b)) group(a)`, ""},
+		{`fields(count())`, ""},
+		{`fields(a, count()) group(a) having(gt(count, 10))`, ""},
+		{`fields(a, max(b)) group(a) having(eq(max_b, "z"))`, ""},
+		{`fields(a, sum(n)) group(a) having(and(gt(sum_n, 1.5), eq(a, "x")))`, ""},
+		{`fields(a, count()) group(a) sort(count desc, a)`, ""},
+		{`fields(median(n))`, "Aggregate [MEDIAN] is not recognized"},
+		{`fields(sum())`, "Aggregate [SUM] requires a field"},
+		{`fields(sum(a))`, "Aggregate [SUM] Field [a] is not a Number"},
+		{`fields(avg(zz))`, "Aggregate [AVG] Field [zz] is not recognized"},
+		{`fields(count(tags))`, "Aggregate [COUNT] Field [tags] can't be aggregated"},
+		{`fields(count(), count())`, "Fields Aggregate [COUNT()] is repeated"},
+		{`fields(a, count()) group(b)`, "Fields Field [a] is not a group field"},
+		{`group(a, A)`, "Group Field [a] is repeated"},
+		{`group(zz)`, "Group Field [zz] is not recognized"},
+		{`group(tags)`, "Group Field [tags] can't be grouped"},
+		{`fields(a, count()) group(a) having(gt(n, 10))`, "Having Function [GT] Field [n] is not recognized"},
+		{`fields(a, count()) group(a) having(eq(count, "x"))`, "Having Function [EQ] Field [count] expects a value of type [INT] not [STRING]"},
+		{`fields(a, max(b)) group(a) having(gt(max_b, 1))`, "Having Function [GT] Field [max_b] expects a value of type [STRING] not [INT]"},
+		{`fields(a, count()) group(a) sort(n)`, "Sort Field [n] is not a group field or aggregate"},
+	}
+
+	for i, tt := range tests {
+		got := verifyQuery(t, testSchema(), tt.input)
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+// Group Fields keep their Schema Definition in Having
+func TestHavingOperators(t *testing.T) {
+	s := testSchema()
+	s.Field("a").AllowOperators("eq", "in")
+
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`fields(a, count()) group(a) having(eq(a, "x"))`, ""},
+		{`fields(a, count()) group(a) having(in(a, "x", "y"))`, ""},
+		{`fields(a, count()) group(a) having(contains(a, "x"))`, "Having Function [CONTAINS] is not allowed on Field [a]"},
+		{`fields(a, count()) group(a) having(and(gt(count, 1), neq(a, "x")))`, "Having Function [NEQ] is not allowed on Field [a]"},
+	}
+
+	for i, tt := range tests {
+		got := verifyQuery(t, s, tt.input)
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	// Deny EQ on Field [a] and any Operator on Field [n] (for Caller "guest")
+	authorize := func(field string, operator string, value *ast.Value, context interface{}) bool {
+		if context != "guest" {
+			return true
+		}
+		return !(field == "a" && operator == "EQ") && field != "n"
+	}
+
+	tests := []struct {
+		input         string
+		context       interface{}
+		expectedError string
+	}{
+		{`eq(a, "x")`, "admin", ""},
+		{`fields(a, count()) group(a) having(eq(a, "x"))`, "admin", ""},
+		{`neq(a, "x") sort(b)`, "guest", ""},
+		{`fields(a, count()) group(a) having(gt(count, 1))`, "guest", ""},
+		{`eq(a, "x")`, "guest", "Function [EQ] on Field [a] is not authorized"},
+		{`gt(x, @n)`, "guest", "Function [GT] on Field [n] is not authorized"},
+		{`fields(n)`, "guest", "Function [FIELDS] on Field [n] is not authorized"},
+		{`sort(n)`, "guest", "Function [SORT] on Field [n] is not authorized"},
+		{`group(n)`, "guest", "Function [GROUP] on Field [n] is not authorized"},
+		{`fields(sum(n))`, "guest", "Function [SUM] on Field [n] is not authorized"},
+		{`fields(a, count()) group(a) having(eq(a, "x"))`, "guest", "Having Function [EQ] on Field [a] is not authorized"},
+	}
+
+	for i, tt := range tests {
+		c := NewSyntaxChecker(parseQuery(t, tt.input))
+		c.Schema = testSchema()
+		c.Authorize = authorize
+		c.Context = tt.context
+
+		got := ""
+		if e := c.Verify(); e != nil {
+			got = e.Message
+		}
+
+		if got != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, got)
+		}
+	}
+}
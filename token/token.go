@@ -27,6 +27,8 @@ const (
 	STRING = "STRING"
 	INT    = "INT"
 	NUMBER = "NUMBER"
+	FIELD  = "FIELD" // Field Reference (@identifier)
+	PARAM  = "PARAM" // Bind Parameter ($identifier or $1)
 
 	// Delimiters
 	COMMA  = ","
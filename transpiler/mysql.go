@@ -12,6 +12,7 @@ package transpiler
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/objectvault/filter-parser/ast"
@@ -24,14 +25,18 @@ func reflectIdentityToFieldMapper(identity string) string {
 	return identity
 }
 
+// Default Spatial Reference System for POINT Literals (WGS 84)
+const DEFAULT_SRID = 4326
+
 type TranspileToMysqlWhere struct {
 	Transpiler
 	Filter      *ast.Filter
 	FieldMapper TMapIdentityToField
+	SRID        int // Spatial Reference System of POINT Fields (0 - Cartesian, no SRID)
 }
 
 func NewTranspileToMysqlWhere(a *ast.Filter, mapper TMapIdentityToField) *TranspileToMysqlWhere {
-	t := &TranspileToMysqlWhere{Filter: a, FieldMapper: reflectIdentityToFieldMapper}
+	t := &TranspileToMysqlWhere{Filter: a, FieldMapper: reflectIdentityToFieldMapper, SRID: DEFAULT_SRID}
 	if mapper != nil {
 		t.FieldMapper = mapper
 	}
@@ -47,6 +52,14 @@ func (c *TranspileToMysqlWhere) Transpile() interface{} {
 func (c *TranspileToMysqlWhere) mysqlFunctionToStatement(p *ast.Function, f *ast.Function) interface{} {
 	// ASSUMPTION: Filter has been run through Syntax Checker so AST is Correct
 	fname := f.Name.Literal
+
+	// Have Unbound Parameters?
+	for _, pi := range f.Parameters {
+		if pv, ok := pi.(*ast.Value); ok && pv.V.Type == token.PARAM { // YES: Filter has to be Bound First
+			return &TranspilerError{Message: fmt.Sprintf("Unbound Parameter [$%s]", pv.V.Literal)}
+		}
+	}
+
 	switch fname {
 	case "NOT":
 		return c.mysqlLogicalNOT(f)
@@ -70,6 +83,32 @@ func (c *TranspileToMysqlWhere) mysqlFunctionToStatement(p *ast.Function, f *ast
 		return c.mysqlOperatorCONTAINS(f)
 	case "IN":
 		return c.mysqlOperatorIN(f)
+	case "STARTSWITH":
+		return c.mysqlOperatorSTARTSWITH(f)
+	case "ENDSWITH":
+		return c.mysqlOperatorENDSWITH(f)
+	case "ICONTAINS":
+		return c.mysqlOperatorICONTAINS(f)
+	case "IEQ":
+		return c.mysqlOperatorIEQ(f)
+	case "MATCHES":
+		return c.mysqlOperatorMATCHES(f)
+	case "BETWEEN":
+		return c.mysqlOperatorBETWEEN(f)
+	case "EXISTS":
+		return c.mysqlOperatorPresence(f, true)
+	case "MISSING":
+		return c.mysqlOperatorPresence(f, false)
+	case "HAS", "HAS_ALL":
+		return c.mysqlOperatorCollection(f, "JSON_CONTAINS")
+	case "HAS_ANY":
+		return c.mysqlOperatorCollection(f, "JSON_OVERLAPS")
+	case "SEARCH":
+		return c.mysqlOperatorSEARCH(f)
+	case "NEAR":
+		return c.mysqlOperatorNEAR(f)
+	case "WITHIN_BOX":
+		return c.mysqlOperatorWITHINBOX(f)
 	default:
 		return &TranspilerError{Message: fmt.Sprintf("Unsupported Funcion [%s]", fname)}
 	}
@@ -181,6 +220,117 @@ func (c *TranspileToMysqlWhere) mysqlOperatorCONTAINS(f *ast.Function) interface
 }
 
 func (c *TranspileToMysqlWhere) mysqlOperatorIN(f *ast.Function) interface{} {
+	// 1st Parameter is the Field, Rest are Values
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	values := make([]string, 0, len(f.Parameters)-1)
+
+	// Single Comma Separated String (i.e. IN(f, "a,b"))?
+	if pv2, ok := f.Parameters[1].(*ast.Value); ok && len(f.Parameters) == 2 && pv2.V.Type == token.STRING && strings.Contains(pv2.V.Literal, ",") { // YES: Split List
+		for _, s := range strings.Split(literalValue(pv2), ",") {
+			values = append(values, fmt.Sprintf("\"%s\"", mysqlEscapeString(strings.TrimSpace(s))))
+		}
+		return fmt.Sprintf("%s IN (%s)", field, strings.Join(values, ", "))
+	}
+
+	for _, pi := range f.Parameters[1:] {
+		v := c.mysqlOperand(pi.(*ast.Value))
+
+		// Converted Value?
+		vs, ok := v.(string)
+		if !ok { // NO: Abort
+			return v
+		}
+
+		values = append(values, vs)
+	}
+
+	return fmt.Sprintf("%s IN (%s)", field, strings.Join(values, ", "))
+}
+
+func (c *TranspileToMysqlWhere) mysqlOperatorSTARTSWITH(f *ast.Function) interface{} {
+	// There should be 2 Parameter
+	// Both Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	pv2 := (f.Parameters[1]).(*ast.Value) // STRING
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	// Value is Matched Literally (no Wildcards)
+	return fmt.Sprintf("%s LIKE \"%s%%\"", field, mysqlEscapeLike(literalValue(pv2)))
+}
+
+func (c *TranspileToMysqlWhere) mysqlOperatorENDSWITH(f *ast.Function) interface{} {
+	// There should be 2 Parameter
+	// Both Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	pv2 := (f.Parameters[1]).(*ast.Value) // STRING
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	// Value is Matched Literally (no Wildcards)
+	return fmt.Sprintf("%s LIKE \"%%%s\"", field, mysqlEscapeLike(literalValue(pv2)))
+}
+
+func (c *TranspileToMysqlWhere) mysqlOperatorICONTAINS(f *ast.Function) interface{} {
+	// There should be 2 Parameter
+	// Both Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	pv2 := (f.Parameters[1]).(*ast.Value) // STRING
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	// Wildcards ('\uFFFD') become '%', Everything else is Matched Literally (including '_')
+	pattern := strings.ReplaceAll(mysqlEscapeLike(pv2.V.Literal), "\uFFFD", "%")
+	return fmt.Sprintf("LOWER(%s) LIKE LOWER(\"%s\")", field, pattern)
+}
+
+func (c *TranspileToMysqlWhere) mysqlOperatorIEQ(f *ast.Function) interface{} {
+	// There should be 2 Parameter
+	// Both Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	pv2 := (f.Parameters[1]).(*ast.Value) // STRING or FIELD
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	// Is Value a Field Reference?
+	if pv2.V.Type == token.FIELD { // YES: Map Referenced Field
+		rfield := c.FieldMapper(pv2.V.Literal)
+
+		// Is Valid Field?
+		if rfield == "" { // NO
+			return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv2.V.Literal)}
+		}
+
+		return fmt.Sprintf("LOWER(%s) = LOWER(%s)", field, rfield)
+	}
+
+	return fmt.Sprintf("LOWER(%s) = LOWER(\"%s\")", field, mysqlEscapeString(literalValue(pv2)))
+}
+
+func (c *TranspileToMysqlWhere) mysqlOperatorMATCHES(f *ast.Function) interface{} {
 	// There should be 2 Parameter
 	// Both Should be ast.Value
 	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
@@ -192,7 +342,157 @@ func (c *TranspileToMysqlWhere) mysqlOperatorIN(f *ast.Function) interface{} {
 		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
 	}
 
-	return fmt.Sprintf("%s IN %q", field, mysqlEscapeValue(pv2))
+	return fmt.Sprintf("%s REGEXP \"%s\"", field, mysqlEscapeString(pv2.Pattern()))
+}
+
+func (c *TranspileToMysqlWhere) mysqlOperatorBETWEEN(f *ast.Function) interface{} {
+	// There should be 3 Parameter
+	// All Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	pv2 := (f.Parameters[1]).(*ast.Value) // Low Bound
+	pv3 := (f.Parameters[2]).(*ast.Value) // High Bound
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	// Convert Bounds
+	low := c.mysqlOperand(pv2)
+	if _, ok := low.(string); !ok {
+		return low
+	}
+
+	high := c.mysqlOperand(pv3)
+	if _, ok := high.(string); !ok {
+		return high
+	}
+
+	return fmt.Sprintf("%s BETWEEN %s AND %s", field, low, high)
+}
+
+// Operators EXISTS and MISSING
+func (c *TranspileToMysqlWhere) mysqlOperatorPresence(f *ast.Function, exists bool) interface{} {
+	// There should be 1 Parameter
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	// Is Field Mapped to a JSON Path (i.e. column->'$.path')?
+	if column, path, ok := mysqlSplitJSONPath(field); ok { // YES: Test for Path in Document
+		if exists {
+			return fmt.Sprintf("JSON_CONTAINS_PATH(%s, 'one', '%s')", column, path)
+		}
+
+		// NOTE: JSON_CONTAINS_PATH is NULL (not FALSE) when the Column is NULL
+		return fmt.Sprintf("(%s IS NULL OR NOT JSON_CONTAINS_PATH(%s, 'one', '%s'))", column, column, path)
+	}
+
+	if exists {
+		return fmt.Sprintf("%s IS NOT NULL", field)
+	}
+	return fmt.Sprintf("%s IS NULL", field)
+}
+
+// Operators HAS, HAS_ANY and HAS_ALL (Field is a JSON Array)
+func (c *TranspileToMysqlWhere) mysqlOperatorCollection(f *ast.Function, op string) interface{} {
+	// 1st Parameter is the Field, Rest are Values
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	values := make([]string, 0, len(f.Parameters)-1)
+
+	for _, pi := range f.Parameters[1:] {
+		v := c.mysqlOperand(pi.(*ast.Value))
+
+		// Converted Value?
+		vs, ok := v.(string)
+		if !ok { // NO: Abort
+			return v
+		}
+
+		values = append(values, vs)
+	}
+
+	return fmt.Sprintf("%s(%s, JSON_ARRAY(%s))", op, field, strings.Join(values, ", "))
+}
+
+// Full Text Search (requires FULLTEXT index over the fields)
+func (c *TranspileToMysqlWhere) mysqlOperatorSEARCH(f *ast.Function) interface{} {
+	// All Parameters, except the Last, are Fields
+	last := len(f.Parameters) - 1
+
+	fields := make([]string, 0, last)
+	for _, pi := range f.Parameters[:last] {
+		pv := pi.(*ast.Value) // Identifier
+
+		// Is Valid Field?
+		field := c.FieldMapper(pv.V.Literal)
+		if field == "" { // NO
+			return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv.V.Literal)}
+		}
+
+		fields = append(fields, field)
+	}
+
+	terms := mysqlSearchTerms((f.Parameters[last]).(*ast.Value))
+	return fmt.Sprintf("MATCH(%s) AGAINST(\"%s\" IN BOOLEAN MODE)", strings.Join(fields, ", "), mysqlEscapeString(terms))
+}
+
+// Distance from Point (in meters)
+func (c *TranspileToMysqlWhere) mysqlOperatorNEAR(f *ast.Function) interface{} {
+	// There should be 4 Parameter
+	// All Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	lat := (f.Parameters[1]).(*ast.Value)
+	lon := (f.Parameters[2]).(*ast.Value)
+	radius := (f.Parameters[3]).(*ast.Value)
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	return fmt.Sprintf("ST_Distance_Sphere(%s, %s) <= %s", field, c.mysqlPoint(lon.V.Literal, lat.V.Literal), radius.V.Literal)
+}
+
+// Inside Bounding Box (South West Corner, North East Corner)
+func (c *TranspileToMysqlWhere) mysqlOperatorWITHINBOX(f *ast.Function) interface{} {
+	// There should be 5 Parameter
+	// All Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	lat1 := (f.Parameters[1]).(*ast.Value)
+	lon1 := (f.Parameters[2]).(*ast.Value)
+	lat2 := (f.Parameters[3]).(*ast.Value)
+	lon2 := (f.Parameters[4]).(*ast.Value)
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	// Does Box Cross the Antimeridian (West Edge is East of the East Edge)?
+	w, _ := strconv.ParseFloat(lon1.V.Literal, 64)
+	e, _ := strconv.ParseFloat(lon2.V.Literal, 64)
+	if w > e { // YES: Split into a Box on Each Side
+		east := c.mysqlEnvelope(lon1.V.Literal, lat1.V.Literal, "180", lat2.V.Literal)
+		west := c.mysqlEnvelope("-180", lat1.V.Literal, lon2.V.Literal, lat2.V.Literal)
+		return fmt.Sprintf("(MBRContains(%s, %s) OR MBRContains(%s, %s))", east, field, west, field)
+	}
+
+	return fmt.Sprintf("MBRContains(%s, %s)", c.mysqlEnvelope(lon1.V.Literal, lat1.V.Literal, lon2.V.Literal, lat2.V.Literal), field)
 }
 
 // HELPERS //
@@ -204,13 +504,13 @@ func (c *TranspileToMysqlWhere) mysqlBinaryLogical(p *ast.Function, op string, f
 	// Converted 1st Function?
 	rs1, ok := r1.(string)
 	if !ok { // NO: Abort
-		return rs1
+		return r1
 	}
 
 	// Converted 2nd Function?
 	rs2, ok := r2.(string)
 	if !ok { // NO: Abort
-		return rs2
+		return r2
 	}
 
 	return fmt.Sprintf("(%s) %s (%s)", rs1, op, rs2)
@@ -225,14 +525,101 @@ func (c *TranspileToMysqlWhere) mysqlBinaryOperator(op string, f *ast.Value, v *
 		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", f.V.Literal)}
 	}
 
-	value := mysqlEscapeValue(v)
-	if v.V.Type == token.STRING {
-		return fmt.Sprintf("%s %s %q", field, op, value)
+	// Is Value a String?
+	if v.V.Type == token.STRING { // YES: Convert '\uFFFD' (replacement for '*') to '%'
+		// NOTE: Only Wildcards are Converted, a '%' in the Value is Matched Literally (Keyset Ties)
+		value := strings.ReplaceAll(mysqlEscapeString(v.V.Literal), "\uFFFD", "%")
+		return fmt.Sprintf("%s %s \"%s\"", field, op, value)
+	}
+
+	value := c.mysqlOperand(v)
+
+	// Converted Value?
+	if _, ok := value.(string); !ok { // NO: Abort
+		return value
 	}
 
 	return fmt.Sprintf("%s %s %s", field, op, value)
 }
 
+// POINT Literal in the Transpiler's Spatial Reference System
+// NOTE: MySQL POINT is (X = Longitude, Y = Latitude)
+func (c *TranspileToMysqlWhere) mysqlPoint(lon string, lat string) string {
+	if c.SRID == 0 {
+		return fmt.Sprintf("POINT(%s, %s)", lon, lat)
+	}
+	return fmt.Sprintf("ST_SRID(POINT(%s, %s), %d)", lon, lat, c.SRID)
+}
+
+// Bounding Box (South West Corner, North East Corner) as a Geometry
+// NOTE: ST_MakeEnvelope only accepts Cartesian Points, so Geographic Boxes are Polygons
+func (c *TranspileToMysqlWhere) mysqlEnvelope(lon1 string, lat1 string, lon2 string, lat2 string) string {
+	if c.SRID == 0 {
+		return fmt.Sprintf("ST_MakeEnvelope(%s, %s)", c.mysqlPoint(lon1, lat1), c.mysqlPoint(lon2, lat2))
+	}
+	return fmt.Sprintf("ST_GeomFromText('POLYGON((%[1]s %[2]s, %[3]s %[2]s, %[3]s %[4]s, %[1]s %[4]s, %[1]s %[2]s))', %[5]d, 'axis-order=long-lat')", lon1, lat1, lon2, lat2, c.SRID)
+}
+
+// Split a JSON Column Path Expression (column->'$.path' or column->>'$.path')
+func mysqlSplitJSONPath(field string) (string, string, bool) {
+	i := strings.Index(field, "->")
+	if i < 0 {
+		return "", "", false
+	}
+
+	column := strings.TrimSpace(field[:i])
+	path := strings.TrimPrefix(field[i+2:], ">")
+	path = strings.Trim(strings.TrimSpace(path), `'"`)
+	return column, path, column != "" && strings.HasPrefix(path, "$")
+}
+
+// Search Terms with BOOLEAN MODE Operators Removed
+// NOTE: A Trailing Wildcard ('\uFFFD') is kept as a Prefix Search ('term*')
+func mysqlSearchTerms(v *ast.Value) string {
+	terms := make([]string, 0)
+	for _, term := range strings.Fields(v.V.Literal) {
+		prefix := strings.HasSuffix(term, "\uFFFD")
+
+		// Remove Operator Characters
+		term = strings.Map(func(r rune) rune {
+			if strings.ContainsRune("+-<>()~*\"@\uFFFD", r) {
+				return -1
+			}
+			return r
+		}, term)
+
+		// Anything Left?
+		if term == "" { // NO: Skip
+			continue
+		}
+
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// Value or Field Reference as a MySQL Operand
+func (c *TranspileToMysqlWhere) mysqlOperand(v *ast.Value) interface{} {
+	switch v.V.Type {
+	case token.FIELD:
+		field := c.FieldMapper(v.V.Literal)
+
+		// Is Valid Field?
+		if field == "" { // NO
+			return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", v.V.Literal)}
+		}
+		return field
+	case token.STRING:
+		return fmt.Sprintf("\"%s\"", mysqlEscapeString(literalValue(v)))
+	}
+
+	return v.V.Literal
+}
+
 func mysqlEscapeValue(v *ast.Value) string {
 	if v.V.Type != token.STRING {
 		return v.V.Literal
@@ -253,3 +640,29 @@ func mysqlEscapeValue(v *ast.Value) string {
 
 	return s
 }
+
+// Escape String for use inside a Double Quoted MySQL String
+func mysqlEscapeString(s string) string {
+	// Escape the Escape Character
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+
+	// Make sure Embedded Quotes are Escaped
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return s
+}
+
+// Escape String for use as a Literal inside a LIKE Pattern
+func mysqlEscapeLike(s string) string {
+	s = mysqlEscapeString(s)
+
+	// Escape LIKE Special Characters
+	s = strings.ReplaceAll(s, `%`, `\%`)
+	s = strings.ReplaceAll(s, `_`, `\_`)
+	return s
+}
+
+// String Value with Wildcards ('\uFFFD') Restored to '*'
+func literalValue(v *ast.Value) string {
+	return strings.ReplaceAll(v.V.Literal, "\uFFFD", "*")
+}
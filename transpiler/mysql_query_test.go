@@ -0,0 +1,143 @@
+package transpiler
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+)
+
+// Transpile Query to a SELECT Statement (returns Error Message on Failure)
+// NOTE: Input is not Verified, so Function Names should be Upper Case
+func transpileQuery(t *testing.T, input string, mapper TMapIdentityToField) string {
+	r := parser.NewParser(lexer.NewLexer(input)).ParseQuery()
+
+	q, ok := r.(*ast.Query)
+	if !ok {
+		t.Fatalf("parse [%s] failed. got=%T (%+v)", input, r, r)
+	}
+
+	switch v := NewTranspileToMysqlQuery(q, mapper).Transpile().(type) {
+	case *MysqlQuery:
+		return v.SQL("items")
+	case *TranspilerError:
+		return v.Message
+	}
+
+	t.Fatalf("transpile [%s] unexpected result", input)
+	return ""
+}
+
+func TestSortAndLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`EQ(a, 1)`, "SELECT * FROM items WHERE t.a = 1"},
+		{`sort(a desc, n)`, "SELECT * FROM items ORDER BY t.a DESC, t.n"},
+		{`limit(10)`, "SELECT * FROM items LIMIT 10"},
+		{`limit(10, offset 20)`, "SELECT * FROM items LIMIT 10 OFFSET 20"},
+		{`EQ(a, 1) sort(n) limit(5, offset 5)`, "SELECT * FROM items WHERE t.a = 1 ORDER BY t.n LIMIT 5 OFFSET 5"},
+		{`sort(zz)`, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpileQuery(t, tt.input, columnMapper)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		input    string
+		mapper   TMapIdentityToField
+		expected string
+	}{
+		{`fields(a, b)`, nil, "SELECT a, b FROM items"},
+		{`fields(a, b)`, columnMapper, "SELECT t.a AS `a`, t.b AS `b` FROM items"},
+		{`fields(a) EQ(n, 1) sort(a)`, columnMapper, "SELECT t.a AS `a` FROM items WHERE t.n = 1 ORDER BY t.a"},
+		{`fields(owner.email)`, nil, "SELECT owner.email FROM items"},
+		{`fields(a, zz)`, columnMapper, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpileQuery(t, tt.input, tt.mapper)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestAggregation(t *testing.T) {
+	tests := []struct {
+		input    string
+		mapper   TMapIdentityToField
+		expected string
+	}{
+		{`fields(COUNT())`, nil, "SELECT COUNT(*) AS `count` FROM items"},
+		{`fields(a, COUNT()) group(a)`, nil, "SELECT a, COUNT(*) AS `count` FROM items GROUP BY a"},
+		{`fields(a, SUM(n)) group(a)`, columnMapper, "SELECT t.a AS `a`, SUM(t.n) AS `sum_n` FROM items GROUP BY t.a"},
+		{`fields(a, COUNT()) EQ(b, "x") group(a) having(GT(count, 10)) sort(count desc) limit(5)`, columnMapper,
+			"SELECT t.a AS `a`, COUNT(*) AS `count` FROM items WHERE t.b = \"x\" GROUP BY t.a HAVING `count` > 10 ORDER BY `count` DESC LIMIT 5"},
+		{`fields(a, MAX(n)) group(a) having(AND(GT(max_n, 1), EQ(a, "x")))`, columnMapper,
+			"SELECT t.a AS `a`, MAX(t.n) AS `max_n` FROM items GROUP BY t.a HAVING (`max_n` > 1) AND (t.a = \"x\")"},
+		{`fields(a, SUM(zz)) group(a)`, columnMapper, "Invalid Field [zz]"},
+		{`fields(zz, COUNT()) group(zz)`, columnMapper, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpileQuery(t, tt.input, tt.mapper)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+// WHERE and HAVING use the Query's Spatial Reference System
+func TestQuerySRID(t *testing.T) {
+	tests := []struct {
+		input    string
+		srid     int
+		expected string
+	}{
+		{`fields(loc, COUNT()) NEAR(loc, 1, 2, 3) group(loc)`, 0,
+			"SELECT t.loc AS `loc`, COUNT(*) AS `count` FROM items WHERE ST_Distance_Sphere(t.loc, POINT(2, 1)) <= 3 GROUP BY t.loc"},
+		{`fields(loc, COUNT()) group(loc) having(NEAR(loc, 1, 2, 3))`, 0,
+			"SELECT t.loc AS `loc`, COUNT(*) AS `count` FROM items GROUP BY t.loc HAVING ST_Distance_Sphere(t.loc, POINT(2, 1)) <= 3"},
+		{`fields(loc, COUNT()) group(loc) having(NEAR(loc, 1, 2, 3))`, 3857,
+			"SELECT t.loc AS `loc`, COUNT(*) AS `count` FROM items GROUP BY t.loc HAVING ST_Distance_Sphere(t.loc, ST_SRID(POINT(2, 1), 3857)) <= 3"},
+	}
+
+	for i, tt := range tests {
+		r := parser.NewParser(lexer.NewLexer(tt.input)).ParseQuery()
+		q, ok := r.(*ast.Query)
+		if !ok {
+			t.Fatalf("tests[%d] - parse failed. got=%T (%+v)", i, r, r)
+		}
+
+		tr := NewTranspileToMysqlQuery(q, columnMapper)
+		tr.SRID = tt.srid
+
+		mq, ok := tr.Transpile().(*MysqlQuery)
+		if !ok {
+			t.Fatalf("tests[%d] - transpile failed", i)
+		}
+
+		if got := mq.SQL("items"); got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
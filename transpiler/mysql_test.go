@@ -0,0 +1,301 @@
+package transpiler
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+)
+
+// Parse Input (Fails Test if Input is not a Valid Filter)
+func parseFilter(t *testing.T, input string) *ast.Filter {
+	r := parser.NewParser(lexer.NewLexer(input)).ParseFilter()
+
+	f, ok := r.(*ast.Filter)
+	if !ok {
+		t.Fatalf("parse [%s] failed. got=%T (%+v)", input, r, r)
+	}
+	return f
+}
+
+// Transpile Input to a WHERE Clause (returns Error Message on Failure)
+// NOTE: Input is not Verified, so Function Names should be Upper Case
+func transpileWhere(t *testing.T, input string, mapper TMapIdentityToField) string {
+	r := NewTranspileToMysqlWhere(parseFilter(t, input), mapper).Transpile()
+
+	switch v := r.(type) {
+	case string:
+		return v
+	case *TranspilerError:
+		return v.Message
+	}
+
+	t.Fatalf("transpile [%s] unexpected result. got=%T", input, r)
+	return ""
+}
+
+// Maps Fields to Prefixed Columns (Unknown Fields are Invalid)
+func columnMapper(identity string) string {
+	switch identity {
+	case "a", "b", "n", "loc":
+		return "t." + identity
+	case "doc":
+		return "t.data->'$.doc'"
+	}
+	return ""
+}
+
+func TestFieldReferences(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`EQ(a, @b)`, "t.a = t.b"},
+		{`NEQ(a, @b)`, "t.a != t.b"},
+		{`GTE(n, @n)`, "t.n >= t.n"},
+		{`IEQ(a, @b)`, "LOWER(t.a) = LOWER(t.b)"},
+		{`EQ(a, @zz)`, "Invalid Field [zz]"},
+		{`IEQ(a, @zz)`, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpileWhere(t, tt.input, columnMapper)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestStringOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`STARTSWITH(a, "abc")`, `t.a LIKE "abc%"`},
+		{`STARTSWITH(a, "5%_off")`, `t.a LIKE "5\%\_off%"`},
+		{`STARTSWITH(a, "a*")`, `t.a LIKE "a*%"`},
+		{`ENDSWITH(a, ".com")`, `t.a LIKE "%.com"`},
+		{`ENDSWITH(a, "a_b")`, `t.a LIKE "%a\_b"`},
+		{`ICONTAINS(a, "*abc*")`, `LOWER(t.a) LIKE LOWER("%abc%")`},
+		{`ICONTAINS(a, "*a_b%c*")`, `LOWER(t.a) LIKE LOWER("%a\_b\%c%")`},
+		{`ICONTAINS(a, "*a\*b*")`, `LOWER(t.a) LIKE LOWER("%a*b%")`},
+		{`IEQ(a, "O'Neil")`, `LOWER(t.a) = LOWER("O\'Neil")`},
+		{`IEQ(a, "a_b*")`, `LOWER(t.a) = LOWER("a_b*")`},
+		{`ICONTAINS(zz, "x")`, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpileWhere(t, tt.input, columnMapper)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`MATCHES(a, "^a.*$")`, `t.a REGEXP "^a.*$"`},
+		{`MATCHES(a, "a\*b")`, `t.a REGEXP "a\\*b"`},
+		{`MATCHES(a, "it's")`, `t.a REGEXP "it\'s"`},
+		{`MATCHES(zz, "a")`, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpileWhere(t, tt.input, columnMapper)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`BETWEEN(n, 1, 10)`, "t.n BETWEEN 1 AND 10"},
+		{`BETWEEN(n, 1.5, 2)`, "t.n BETWEEN 1.5 AND 2"},
+		{`BETWEEN(a, "a", "m\"")`, `t.a BETWEEN "a" AND "m\""`},
+		{`BETWEEN(n, @n, 10)`, "t.n BETWEEN t.n AND 10"},
+		{`BETWEEN(n, 1, @zz)`, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpileWhere(t, tt.input, columnMapper)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestPresence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`EXISTS(a)`, "t.a IS NOT NULL"},
+		{`MISSING(a)`, "t.a IS NULL"},
+		{`EXISTS(doc)`, "JSON_CONTAINS_PATH(t.data, 'one', '$.doc')"},
+		{`MISSING(doc)`, "(t.data IS NULL OR NOT JSON_CONTAINS_PATH(t.data, 'one', '$.doc'))"},
+		{`NOT(MISSING(doc))`, "NOT((t.data IS NULL OR NOT JSON_CONTAINS_PATH(t.data, 'one', '$.doc')))"},
+		{`EXISTS(zz)`, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpileWhere(t, tt.input, columnMapper)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestCollections(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`HAS(a, "x")`, `JSON_CONTAINS(t.a, JSON_ARRAY("x"))`},
+		{`HAS_ALL(a, "x", "y")`, `JSON_CONTAINS(t.a, JSON_ARRAY("x", "y"))`},
+		{`HAS(a, "x,y")`, `JSON_CONTAINS(t.a, JSON_ARRAY("x,y"))`},
+		{`HAS_ANY(n, 1, 2)`, "JSON_OVERLAPS(t.n, JSON_ARRAY(1, 2))"},
+		{`HAS_ANY(zz, 1)`, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpileWhere(t, tt.input, columnMapper)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestIN(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`IN(a, "x")`, `t.a IN ("x")`},
+		{`IN(a, "x", "O'Neil")`, `t.a IN ("x", "O\'Neil")`},
+		{`IN(a, "x,y")`, `t.a IN ("x", "y")`},
+		{`IN(a, "x, O'Neil ,z")`, `t.a IN ("x", "O\'Neil", "z")`},
+		{`IN(a, "x,y", "z")`, `t.a IN ("x,y", "z")`},
+		{`IN(n, 1, 2, 3)`, "t.n IN (1, 2, 3)"},
+		{`NOT(IN(n, 1, 2))`, "NOT(t.n IN (1, 2))"},
+		{`IN(n, 1, $1)`, "Unbound Parameter [$1]"},
+		{`IN(zz, 1)`, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpileWhere(t, tt.input, columnMapper)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`SEARCH(a, "hello world")`, `MATCH(t.a) AGAINST("hello world" IN BOOLEAN MODE)`},
+		{`SEARCH(a, b, "+foo -bar* (x)")`, `MATCH(t.a, t.b) AGAINST("foo bar* x" IN BOOLEAN MODE)`},
+		{`SEARCH(a, "it's ~\"q\"")`, `MATCH(t.a) AGAINST("it\'s q" IN BOOLEAN MODE)`},
+		{`SEARCH(a, zz, "x")`, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpileWhere(t, tt.input, columnMapper)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestGeo(t *testing.T) {
+	tests := []struct {
+		input    string
+		srid     int
+		expected string
+	}{
+		{`NEAR(loc, 40.7, -74.0, 500)`, DEFAULT_SRID, "ST_Distance_Sphere(t.loc, ST_SRID(POINT(-74.0, 40.7), 4326)) <= 500"},
+		{`NEAR(loc, 40.7, -74.0, 500)`, 0, "ST_Distance_Sphere(t.loc, POINT(-74.0, 40.7)) <= 500"},
+		{`WITHIN_BOX(loc, 10, -10, 20, 10)`, DEFAULT_SRID, "MBRContains(ST_GeomFromText('POLYGON((-10 10, 10 10, 10 20, -10 20, -10 10))', 4326, 'axis-order=long-lat'), t.loc)"},
+		{`WITHIN_BOX(loc, 10, -10, 20, 10)`, 0, "MBRContains(ST_MakeEnvelope(POINT(-10, 10), POINT(10, 20)), t.loc)"},
+		{`WITHIN_BOX(loc, 10, 170, 20, -170)`, 0, "(MBRContains(ST_MakeEnvelope(POINT(170, 10), POINT(180, 20)), t.loc) OR MBRContains(ST_MakeEnvelope(POINT(-180, 10), POINT(-170, 20)), t.loc))"},
+		{`WITHIN_BOX(loc, 10, 170, 20, -170)`, 3857, "(MBRContains(ST_GeomFromText('POLYGON((170 10, 180 10, 180 20, 170 20, 170 10))', 3857, 'axis-order=long-lat'), t.loc) OR MBRContains(ST_GeomFromText('POLYGON((-180 10, -170 10, -170 20, -180 20, -180 10))', 3857, 'axis-order=long-lat'), t.loc))"},
+		{`NEAR(zz, 0, 0, 1)`, DEFAULT_SRID, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		tr := NewTranspileToMysqlWhere(parseFilter(t, tt.input), columnMapper)
+		tr.SRID = tt.srid
+
+		got := tr.Transpile()
+		if e, ok := got.(*TranspilerError); ok {
+			got = e.Message
+		}
+
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestComparisons(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`EQ(a, "abc")`, `t.a = "abc"`},
+		{`EQ(a, "50%_off")`, `t.a = "50%_off"`},
+		{`EQ(a, "a*")`, `t.a = "a%"`},
+		{`EQ(a, "a\*")`, `t.a = "a*"`},
+		{`NEQ(a, "*50%*")`, `t.a != "%50%%"`},
+		{`EQ(a, "a\\b")`, `t.a = "a\\b"`},
+		{`NEQ(a, "O'Neil \"Jr\"")`, `t.a != "O\'Neil \"Jr\""`},
+		{`GT(n, 5)`, "t.n > 5"},
+		{`LTE(n, -1.5)`, "t.n <= -1.5"},
+		{`LT(zz, 1)`, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpileWhere(t, tt.input, columnMapper)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestUnboundParams(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`EQ(a, $name)`, "Unbound Parameter [$name]"},
+		{`AND(EQ(a, "x"), GT(n, $1))`, "Unbound Parameter [$1]"},
+		{`OR(EQ(a, $1), GT(n, 1))`, "Unbound Parameter [$1]"},
+		{`NOT(EQ(a, $1))`, "Unbound Parameter [$1]"},
+	}
+
+	for i, tt := range tests {
+		got := transpileWhere(t, tt.input, columnMapper)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
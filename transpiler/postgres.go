@@ -0,0 +1,524 @@
+package transpiler
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/token"
+)
+
+/*
+  POSTGRESQL
+  Same operators as the MySQL transpiler, in the PostgreSQL dialect:
+  - strings are single quoted ('' escapes a quote), backslashes are
+    literal (standard_conforming_strings = on, the default since 9.1)
+  - MATCHES is "~", ICONTAINS is ILIKE
+  - collections are jsonb arrays (@>)
+  - SEARCH is to_tsvector(...) @@ to_tsquery(...)
+  - geo operators require PostGIS
+*/
+
+type TranspileToPostgresWhere struct {
+	Transpiler
+	Filter      *ast.Filter
+	FieldMapper TMapIdentityToField
+	SRID        int // Spatial Reference System of POINT Fields (0 - Cartesian, no SRID)
+}
+
+func NewTranspileToPostgresWhere(a *ast.Filter, mapper TMapIdentityToField) *TranspileToPostgresWhere {
+	t := &TranspileToPostgresWhere{Filter: a, FieldMapper: reflectIdentityToFieldMapper, SRID: DEFAULT_SRID}
+	if mapper != nil {
+		t.FieldMapper = mapper
+	}
+
+	return t
+}
+
+func (c *TranspileToPostgresWhere) Transpile() interface{} {
+	f := c.Filter.F
+	return c.postgresFunctionToStatement(f)
+}
+
+func (c *TranspileToPostgresWhere) postgresFunctionToStatement(f *ast.Function) interface{} {
+	// ASSUMPTION: Filter has been run through Syntax Checker so AST is Correct
+	fname := f.Name.Literal
+
+	// Have Unbound Parameters?
+	for _, pi := range f.Parameters {
+		if pv, ok := pi.(*ast.Value); ok && pv.V.Type == token.PARAM { // YES: Filter has to be Bound First
+			return &TranspilerError{Message: fmt.Sprintf("Unbound Parameter [$%s]", pv.V.Literal)}
+		}
+	}
+
+	switch fname {
+	case "NOT":
+		return c.postgresLogicalNOT(f)
+	case "AND":
+		return c.postgresBinaryLogical("AND", f)
+	case "OR":
+		return c.postgresBinaryLogical("OR", f)
+	case "EQ":
+		return c.postgresBinaryOperator("=", f)
+	case "NEQ":
+		return c.postgresBinaryOperator("!=", f)
+	case "GT":
+		return c.postgresBinaryOperator(">", f)
+	case "GTE":
+		return c.postgresBinaryOperator(">=", f)
+	case "LT":
+		return c.postgresBinaryOperator("<", f)
+	case "LTE":
+		return c.postgresBinaryOperator("<=", f)
+	case "CONTAINS":
+		return c.postgresOperatorLIKE(f, "LIKE")
+	case "ICONTAINS":
+		return c.postgresOperatorLIKE(f, "ILIKE")
+	case "IN":
+		return c.postgresOperatorIN(f)
+	case "STARTSWITH":
+		return c.postgresOperatorAffix(f, "", "%")
+	case "ENDSWITH":
+		return c.postgresOperatorAffix(f, "%", "")
+	case "IEQ":
+		return c.postgresOperatorIEQ(f)
+	case "MATCHES":
+		return c.postgresOperatorMATCHES(f)
+	case "BETWEEN":
+		return c.postgresOperatorBETWEEN(f)
+	case "EXISTS":
+		return c.postgresOperatorPresence(f, "IS NOT NULL")
+	case "MISSING":
+		return c.postgresOperatorPresence(f, "IS NULL")
+	case "HAS", "HAS_ALL":
+		return c.postgresOperatorCollection(f, false)
+	case "HAS_ANY":
+		return c.postgresOperatorCollection(f, true)
+	case "SEARCH":
+		return c.postgresOperatorSEARCH(f)
+	case "NEAR":
+		return c.postgresOperatorNEAR(f)
+	case "WITHIN_BOX":
+		return c.postgresOperatorWITHINBOX(f)
+	default:
+		return &TranspilerError{Message: fmt.Sprintf("Unsupported Funcion [%s]", fname)}
+	}
+}
+
+// Logical NOT
+func (c *TranspileToPostgresWhere) postgresLogicalNOT(fnot *ast.Function) interface{} {
+	// 1st Parameter to NOT Should be a Logical Function or Operator Function
+	pf1 := (fnot.Parameters[0]).(*ast.Function)
+	r := c.postgresFunctionToStatement(pf1)
+
+	// Converted Function?
+	rs, ok := r.(string)
+	if !ok { // NO: Abort
+		return r
+	}
+
+	return fmt.Sprintf("NOT(%s)", rs)
+}
+
+// Logical AND and OR
+func (c *TranspileToPostgresWhere) postgresBinaryLogical(op string, f *ast.Function) interface{} {
+	// There should be 2 Parameter
+	// Both Should be ast.Function
+	r1 := c.postgresFunctionToStatement((f.Parameters[0]).(*ast.Function))
+	r2 := c.postgresFunctionToStatement((f.Parameters[1]).(*ast.Function))
+
+	// Converted 1st Function?
+	rs1, ok := r1.(string)
+	if !ok { // NO: Abort
+		return r1
+	}
+
+	// Converted 2nd Function?
+	rs2, ok := r2.(string)
+	if !ok { // NO: Abort
+		return r2
+	}
+
+	return fmt.Sprintf("(%s) %s (%s)", rs1, op, rs2)
+}
+
+// Operators EQ, NEQ, GT, GTE, LT and LTE
+func (c *TranspileToPostgresWhere) postgresBinaryOperator(op string, f *ast.Function) interface{} {
+	// There should be 2 Parameter
+	// Both Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	pv2 := (f.Parameters[1]).(*ast.Value)
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	// Is Value a String?
+	if pv2.V.Type == token.STRING { // YES: Convert '\uFFFD' (replacement for '*') to '%'
+		// NOTE: Only Wildcards are Converted, a '%' in the Value is Matched Literally (Keyset Ties)
+		value := strings.ReplaceAll(postgresEscapeString(pv2.V.Literal), "\uFFFD", "%")
+		return fmt.Sprintf("%s %s '%s'", field, op, value)
+	}
+
+	value := c.postgresOperand(pv2)
+
+	// Converted Value?
+	if _, ok := value.(string); !ok { // NO: Abort
+		return value
+	}
+
+	return fmt.Sprintf("%s %s %s", field, op, value)
+}
+
+// Operators CONTAINS (LIKE) and ICONTAINS (ILIKE)
+func (c *TranspileToPostgresWhere) postgresOperatorLIKE(f *ast.Function, op string) interface{} {
+	// There should be 2 Parameter
+	// Both Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	pv2 := (f.Parameters[1]).(*ast.Value) // STRING
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	// Wildcards ('\uFFFD') become '%', Everything else is Matched Literally (including '_')
+	pattern := strings.ReplaceAll(postgresEscapeLike(pv2.V.Literal), "\uFFFD", "%")
+	return fmt.Sprintf("%s %s '%s'", field, op, pattern)
+}
+
+// Operators STARTSWITH and ENDSWITH
+func (c *TranspileToPostgresWhere) postgresOperatorAffix(f *ast.Function, before string, after string) interface{} {
+	// There should be 2 Parameter
+	// Both Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	pv2 := (f.Parameters[1]).(*ast.Value) // STRING
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	// Value is Matched Literally (no Wildcards)
+	return fmt.Sprintf("%s LIKE '%s%s%s'", field, before, postgresEscapeLike(literalValue(pv2)), after)
+}
+
+func (c *TranspileToPostgresWhere) postgresOperatorIN(f *ast.Function) interface{} {
+	// 1st Parameter is the Field, Rest are Values
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	values := make([]string, 0, len(f.Parameters)-1)
+
+	// Single Comma Separated String (i.e. IN(f, "a,b"))?
+	if pv2, ok := f.Parameters[1].(*ast.Value); ok && len(f.Parameters) == 2 && pv2.V.Type == token.STRING && strings.Contains(pv2.V.Literal, ",") { // YES: Split List
+		for _, s := range strings.Split(literalValue(pv2), ",") {
+			values = append(values, fmt.Sprintf("'%s'", postgresEscapeString(strings.TrimSpace(s))))
+		}
+		return fmt.Sprintf("%s IN (%s)", field, strings.Join(values, ", "))
+	}
+
+	r := c.postgresOperands(f.Parameters[1:])
+
+	// Converted Values?
+	values, ok := r.([]string)
+	if !ok { // NO: Abort
+		return r
+	}
+
+	return fmt.Sprintf("%s IN (%s)", field, strings.Join(values, ", "))
+}
+
+func (c *TranspileToPostgresWhere) postgresOperatorIEQ(f *ast.Function) interface{} {
+	// There should be 2 Parameter
+	// Both Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	pv2 := (f.Parameters[1]).(*ast.Value) // STRING or FIELD
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	value := c.postgresOperand(pv2)
+
+	// Converted Value?
+	if _, ok := value.(string); !ok { // NO: Abort
+		return value
+	}
+
+	return fmt.Sprintf("LOWER(%s) = LOWER(%s)", field, value)
+}
+
+func (c *TranspileToPostgresWhere) postgresOperatorMATCHES(f *ast.Function) interface{} {
+	// There should be 2 Parameter
+	// Both Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	pv2 := (f.Parameters[1]).(*ast.Value) // STRING
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	return fmt.Sprintf("%s ~ '%s'", field, postgresEscapeString(pv2.Pattern()))
+}
+
+func (c *TranspileToPostgresWhere) postgresOperatorBETWEEN(f *ast.Function) interface{} {
+	// There should be 3 Parameter
+	// All Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	// Convert Bounds
+	r := c.postgresOperands(f.Parameters[1:])
+	bounds, ok := r.([]string)
+	if !ok { // NO: Abort
+		return r
+	}
+
+	return fmt.Sprintf("%s BETWEEN %s AND %s", field, bounds[0], bounds[1])
+}
+
+// Operators EXISTS and MISSING
+// NOTE: A Field Mapped to a jsonb Path (i.e. data->'path') is NULL if the Path is Missing
+func (c *TranspileToPostgresWhere) postgresOperatorPresence(f *ast.Function, test string) interface{} {
+	// There should be 1 Parameter
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	return fmt.Sprintf("%s %s", field, test)
+}
+
+// Operators HAS, HAS_ANY and HAS_ALL (Field is a jsonb Array)
+func (c *TranspileToPostgresWhere) postgresOperatorCollection(f *ast.Function, anyOf bool) interface{} {
+	// 1st Parameter is the Field, Rest are Values
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	r := c.postgresOperands(f.Parameters[1:])
+
+	// Converted Values?
+	values, ok := r.([]string)
+	if !ok { // NO: Abort
+		return r
+	}
+
+	// Contains All Values?
+	if !anyOf || len(values) == 1 { // YES: Array Contains the List
+		return fmt.Sprintf("%s @> jsonb_build_array(%s)", field, strings.Join(values, ", "))
+	}
+
+	// NOTE: ?| only matches String Elements, so Values are Tested one by one
+	tests := make([]string, 0, len(values))
+	for _, v := range values {
+		tests = append(tests, fmt.Sprintf("%s @> jsonb_build_array(%s)", field, v))
+	}
+	return fmt.Sprintf("(%s)", strings.Join(tests, " OR "))
+}
+
+// Full Text Search (any of the Terms, use an Expression Index over the Fields)
+func (c *TranspileToPostgresWhere) postgresOperatorSEARCH(f *ast.Function) interface{} {
+	// All Parameters, except the Last, are Fields
+	last := len(f.Parameters) - 1
+
+	fields := make([]string, 0, last)
+	for _, pi := range f.Parameters[:last] {
+		pv := pi.(*ast.Value) // Identifier
+
+		// Is Valid Field?
+		field := c.FieldMapper(pv.V.Literal)
+		if field == "" { // NO
+			return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv.V.Literal)}
+		}
+
+		fields = append(fields, field)
+	}
+
+	document := fields[0]
+	if len(fields) > 1 {
+		document = fmt.Sprintf("concat_ws(' ', %s)", strings.Join(fields, ", "))
+	}
+
+	terms := postgresSearchTerms((f.Parameters[last]).(*ast.Value))
+	return fmt.Sprintf("to_tsvector(%s) @@ to_tsquery('%s')", document, postgresEscapeString(terms))
+}
+
+// Distance from Point (in meters)
+func (c *TranspileToPostgresWhere) postgresOperatorNEAR(f *ast.Function) interface{} {
+	// There should be 4 Parameter
+	// All Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	lat := (f.Parameters[1]).(*ast.Value)
+	lon := (f.Parameters[2]).(*ast.Value)
+	radius := (f.Parameters[3]).(*ast.Value)
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	return fmt.Sprintf("ST_DistanceSphere(%s, %s) <= %s", field, c.postgresPoint(lon.V.Literal, lat.V.Literal), radius.V.Literal)
+}
+
+// Inside Bounding Box (South West Corner, North East Corner)
+func (c *TranspileToPostgresWhere) postgresOperatorWITHINBOX(f *ast.Function) interface{} {
+	// There should be 5 Parameter
+	// All Should be ast.Value
+	pv1 := (f.Parameters[0]).(*ast.Value) // Identifier
+	lat1 := (f.Parameters[1]).(*ast.Value)
+	lon1 := (f.Parameters[2]).(*ast.Value)
+	lat2 := (f.Parameters[3]).(*ast.Value)
+	lon2 := (f.Parameters[4]).(*ast.Value)
+
+	// Is Valid Field?
+	field := c.FieldMapper(pv1.V.Literal)
+	if field == "" { // NO
+		return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", pv1.V.Literal)}
+	}
+
+	// Does Box Cross the Antimeridian (West Edge is East of the East Edge)?
+	w, _ := strconv.ParseFloat(lon1.V.Literal, 64)
+	e, _ := strconv.ParseFloat(lon2.V.Literal, 64)
+	if w > e { // YES: Split into a Box on Each Side
+		east := c.postgresEnvelope(lon1.V.Literal, lat1.V.Literal, "180", lat2.V.Literal)
+		west := c.postgresEnvelope("-180", lat1.V.Literal, lon2.V.Literal, lat2.V.Literal)
+		return fmt.Sprintf("(ST_Contains(%s, %s) OR ST_Contains(%s, %s))", east, field, west, field)
+	}
+
+	return fmt.Sprintf("ST_Contains(%s, %s)", c.postgresEnvelope(lon1.V.Literal, lat1.V.Literal, lon2.V.Literal, lat2.V.Literal), field)
+}
+
+// HELPERS //
+
+// POINT Literal in the Transpiler's Spatial Reference System (X = Longitude, Y = Latitude)
+func (c *TranspileToPostgresWhere) postgresPoint(lon string, lat string) string {
+	if c.SRID == 0 {
+		return fmt.Sprintf("ST_MakePoint(%s, %s)", lon, lat)
+	}
+	return fmt.Sprintf("ST_SetSRID(ST_MakePoint(%s, %s), %d)", lon, lat, c.SRID)
+}
+
+// Bounding Box (South West Corner, North East Corner) as a Geometry
+func (c *TranspileToPostgresWhere) postgresEnvelope(lon1 string, lat1 string, lon2 string, lat2 string) string {
+	if c.SRID == 0 {
+		return fmt.Sprintf("ST_MakeEnvelope(%s, %s, %s, %s)", lon1, lat1, lon2, lat2)
+	}
+	return fmt.Sprintf("ST_MakeEnvelope(%s, %s, %s, %s, %d)", lon1, lat1, lon2, lat2, c.SRID)
+}
+
+// Search Terms as a tsquery (Terms OR'ed, tsquery Operators Removed)
+// NOTE: A Trailing Wildcard ('\uFFFD') is kept as a Prefix Search ('term:*')
+func postgresSearchTerms(v *ast.Value) string {
+	terms := make([]string, 0)
+	for _, term := range strings.Fields(v.V.Literal) {
+		prefix := strings.HasSuffix(term, "\uFFFD")
+
+		// Remove Operator Characters
+		term = strings.Map(func(r rune) rune {
+			if strings.ContainsRune("&|!:()<>+-~*'\"\\@\uFFFD", r) {
+				return -1
+			}
+			return r
+		}, term)
+
+		// Anything Left?
+		if term == "" { // NO: Skip
+			continue
+		}
+
+		if prefix {
+			term += ":*"
+		}
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, " | ")
+}
+
+// Values or Field References as PostgreSQL Operands
+func (c *TranspileToPostgresWhere) postgresOperands(ps []interface{}) interface{} {
+	values := make([]string, 0, len(ps))
+	for _, pi := range ps {
+		v := c.postgresOperand(pi.(*ast.Value))
+
+		// Converted Value?
+		vs, ok := v.(string)
+		if !ok { // NO: Abort
+			return v
+		}
+
+		values = append(values, vs)
+	}
+
+	return values
+}
+
+// Value or Field Reference as a PostgreSQL Operand
+func (c *TranspileToPostgresWhere) postgresOperand(v *ast.Value) interface{} {
+	switch v.V.Type {
+	case token.FIELD:
+		field := c.FieldMapper(v.V.Literal)
+
+		// Is Valid Field?
+		if field == "" { // NO
+			return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", v.V.Literal)}
+		}
+		return field
+	case token.STRING:
+		return fmt.Sprintf("'%s'", postgresEscapeString(literalValue(v)))
+	}
+
+	return v.V.Literal
+}
+
+// Escape String for use inside a Single Quoted PostgreSQL String
+func postgresEscapeString(s string) string {
+	return strings.ReplaceAll(s, `'`, `''`)
+}
+
+// Escape String for use as a Literal inside a LIKE Pattern ('\' is the LIKE Escape Character)
+func postgresEscapeLike(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `%`, `\%`)
+	s = strings.ReplaceAll(s, `_`, `\_`)
+	return postgresEscapeString(s)
+}
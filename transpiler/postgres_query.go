@@ -0,0 +1,249 @@
+package transpiler
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/objectvault/filter-parser/ast"
+)
+
+// Transpiled Query Clauses (empty string - clause not present)
+type PostgresQuery struct {
+	Select  string
+	Where   string
+	GroupBy string
+	Having  string
+	OrderBy string
+	Limit   string
+}
+
+// Complete SELECT Statement for Table
+func (q *PostgresQuery) SQL(table string) string {
+	columns := q.Select
+	if columns == "" {
+		columns = "*"
+	}
+
+	sql := fmt.Sprintf("SELECT %s FROM %s", columns, table)
+	if clauses := q.ToString(); clauses != "" {
+		sql += " " + clauses
+	}
+	return sql
+}
+
+// Clauses that Follow the FROM
+func (q *PostgresQuery) ToString() string {
+	clauses := make([]string, 0, 5)
+	if q.Where != "" {
+		clauses = append(clauses, "WHERE "+q.Where)
+	}
+	if q.GroupBy != "" {
+		clauses = append(clauses, "GROUP BY "+q.GroupBy)
+	}
+	if q.Having != "" {
+		clauses = append(clauses, "HAVING "+q.Having)
+	}
+	if q.OrderBy != "" {
+		clauses = append(clauses, "ORDER BY "+q.OrderBy)
+	}
+	if q.Limit != "" {
+		clauses = append(clauses, "LIMIT "+q.Limit)
+	}
+	return strings.Join(clauses, " ")
+}
+
+type TranspileToPostgresQuery struct {
+	Transpiler
+	Query       *ast.Query
+	FieldMapper TMapIdentityToField
+	SRID        int // Spatial Reference System of POINT Fields (0 - Cartesian, no SRID)
+}
+
+func NewTranspileToPostgresQuery(q *ast.Query, mapper TMapIdentityToField) *TranspileToPostgresQuery {
+	t := &TranspileToPostgresQuery{Query: q, FieldMapper: reflectIdentityToFieldMapper, SRID: DEFAULT_SRID}
+	if mapper != nil {
+		t.FieldMapper = mapper
+	}
+
+	return t
+}
+
+func (c *TranspileToPostgresQuery) Transpile() interface{} {
+	// ASSUMPTION: Query has been run through Syntax Checker so AST is Correct
+	q := &PostgresQuery{}
+
+	// Have Projection?
+	if c.Query.Fields != nil { // YES: Transpile Column List
+		r := c.postgresSelect(c.Query.Fields)
+
+		// Converted Projection?
+		rs, ok := r.(string)
+		if !ok { // NO: Abort
+			return r
+		}
+		q.Select = rs
+	}
+
+	// Have Filter?
+	if c.Query.Filter != nil { // YES: Transpile WHERE Condition
+		w := NewTranspileToPostgresWhere(c.Query.Filter, c.FieldMapper)
+		w.SRID = c.SRID
+		r := w.Transpile()
+
+		// Converted Filter?
+		rs, ok := r.(string)
+		if !ok { // NO: Abort
+			return r
+		}
+		q.Where = rs
+	}
+
+	// Have Group?
+	if c.Query.Group != nil { // YES: Transpile GROUP BY
+		r := c.postgresGroupBy(c.Query.Group)
+
+		// Converted Group?
+		rs, ok := r.(string)
+		if !ok { // NO: Abort
+			return r
+		}
+		q.GroupBy = rs
+	}
+
+	// Have Having?
+	if c.Query.Having != nil { // YES: Transpile HAVING Condition (Aggregates by Alias)
+		h := NewTranspileToPostgresWhere(c.Query.Having, c.aggregateFieldMapper)
+		h.SRID = c.SRID
+		r := h.Transpile()
+
+		// Converted Having?
+		rs, ok := r.(string)
+		if !ok { // NO: Abort
+			return r
+		}
+		q.Having = rs
+	}
+
+	// Have Sort?
+	if c.Query.Sort != nil { // YES: Transpile ORDER BY
+		r := c.postgresOrderBy(c.Query.Sort)
+
+		// Converted Sort?
+		rs, ok := r.(string)
+		if !ok { // NO: Abort
+			return r
+		}
+		q.OrderBy = rs
+	}
+
+	// Have Limit?
+	if c.Query.Limit != nil { // YES: Transpile LIMIT
+		q.Limit = c.postgresLimit(c.Query.Limit)
+	}
+
+	return q
+}
+
+func (c *TranspileToPostgresQuery) postgresSelect(fl *ast.Fields) interface{} {
+	columns := make([]string, 0, len(fl.Fields))
+	for _, f := range fl.Fields {
+		// Is Valid Field?
+		column := c.FieldMapper(f.Literal)
+		if column == "" { // NO
+			return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", f.Literal)}
+		}
+
+		// Column Name Differs from Field?
+		if column != f.Literal { // YES: Return Column with Field Name
+			column = fmt.Sprintf("%s AS \"%s\"", column, f.Literal)
+		}
+		columns = append(columns, column)
+	}
+
+	for _, a := range fl.Aggregates {
+		// COUNT() counts Rows
+		column := "*"
+		if a.Field != nil {
+			// Is Valid Field?
+			column = c.FieldMapper(a.Field.Literal)
+			if column == "" { // NO
+				return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", a.Field.Literal)}
+			}
+		}
+
+		columns = append(columns, fmt.Sprintf("%s(%s) AS \"%s\"", strings.ToUpper(a.Function.Literal), column, a.Alias()))
+	}
+
+	return strings.Join(columns, ", ")
+}
+
+func (c *TranspileToPostgresQuery) postgresGroupBy(g *ast.Group) interface{} {
+	fields := make([]string, 0, len(g.Fields))
+	for _, f := range g.Fields {
+		// Is Valid Field?
+		field := c.FieldMapper(f.Literal)
+		if field == "" { // NO
+			return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", f.Literal)}
+		}
+
+		fields = append(fields, field)
+	}
+
+	return strings.Join(fields, ", ")
+}
+
+// Field Mapper that Maps Aggregate Aliases to their Result Column
+// NOTE: PostgreSQL doesn't allow Aliases in HAVING, so the Aggregate is Repeated
+func (c *TranspileToPostgresQuery) aggregateFieldMapper(identity string) string {
+	if c.Query.Fields != nil {
+		for _, a := range c.Query.Fields.Aggregates {
+			if a.Alias() != identity {
+				continue
+			}
+
+			// COUNT() counts Rows
+			column := "*"
+			if a.Field != nil {
+				column = c.FieldMapper(a.Field.Literal)
+			}
+			return fmt.Sprintf("%s(%s)", strings.ToUpper(a.Function.Literal), column)
+		}
+	}
+
+	return c.FieldMapper(identity)
+}
+
+func (c *TranspileToPostgresQuery) postgresOrderBy(s *ast.Sort) interface{} {
+	fields := make([]string, 0, len(s.Fields))
+	for _, sf := range s.Fields {
+		// Is Valid Field?
+		field := c.aggregateFieldMapper(sf.Field.Literal)
+		if field == "" { // NO
+			return &TranspilerError{Message: fmt.Sprintf("Invalid Field [%s]", sf.Field.Literal)}
+		}
+
+		if sf.Descending {
+			field += " DESC"
+		}
+		fields = append(fields, field)
+	}
+
+	return strings.Join(fields, ", ")
+}
+
+func (c *TranspileToPostgresQuery) postgresLimit(l *ast.Limit) string {
+	if l.Offset != nil {
+		return fmt.Sprintf("%s OFFSET %s", l.Count.Literal, l.Offset.Literal)
+	}
+	return l.Count.Literal
+}
@@ -0,0 +1,59 @@
+package transpiler
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+
+	"github.com/objectvault/filter-parser/ast"
+	"github.com/objectvault/filter-parser/lexer"
+	"github.com/objectvault/filter-parser/parser"
+)
+
+func TestPostgresQuery(t *testing.T) {
+	tests := []struct {
+		input    string
+		mapper   TMapIdentityToField
+		expected string
+	}{
+		{`EQ(a, "x") sort(a desc, n) limit(10, offset 20)`, columnMapper, "SELECT * FROM items WHERE t.a = 'x' ORDER BY t.a DESC, t.n LIMIT 10 OFFSET 20"},
+		{`fields(a, b)`, nil, "SELECT a, b FROM items"},
+		{`fields(a, b)`, columnMapper, `SELECT t.a AS "a", t.b AS "b" FROM items`},
+		{`fields(a, COUNT()) EQ(b, "x") group(a) having(GT(count, 10)) sort(count desc) limit(5)`, columnMapper,
+			`SELECT t.a AS "a", COUNT(*) AS "count" FROM items WHERE t.b = 'x' GROUP BY t.a HAVING COUNT(*) > 10 ORDER BY COUNT(*) DESC LIMIT 5`},
+		{`fields(a, MAX(n)) group(a) having(AND(GT(max_n, 1), EQ(a, "x")))`, columnMapper,
+			`SELECT t.a AS "a", MAX(t.n) AS "max_n" FROM items GROUP BY t.a HAVING (MAX(t.n) > 1) AND (t.a = 'x')`},
+		{`fields(loc, COUNT()) group(loc) having(NEAR(loc, 1, 2, 3))`, columnMapper,
+			`SELECT t.loc AS "loc", COUNT(*) AS "count" FROM items GROUP BY t.loc HAVING ST_DistanceSphere(t.loc, ST_SetSRID(ST_MakePoint(2, 1), 4326)) <= 3`},
+		{`fields(a, SUM(zz)) group(a)`, columnMapper, "Invalid Field [zz]"},
+		{`sort(zz)`, columnMapper, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		r := parser.NewParser(lexer.NewLexer(tt.input)).ParseQuery()
+		q, ok := r.(*ast.Query)
+		if !ok {
+			t.Fatalf("tests[%d] - parse failed. got=%T (%+v)", i, r, r)
+		}
+
+		var got string
+		switch v := NewTranspileToPostgresQuery(q, tt.mapper).Transpile().(type) {
+		case *PostgresQuery:
+			got = v.SQL("items")
+		case *TranspilerError:
+			got = v.Message
+		}
+
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
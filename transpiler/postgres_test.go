@@ -0,0 +1,139 @@
+package transpiler
+
+/*
+ * This file is part of the ObjectVault Project.
+ * Copyright (C) 2020-2022 Paulo Ferreira <vault at sourcenotes.org>
+ *
+ * This work is published under the GNU AGPLv3.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+import (
+	"testing"
+)
+
+// Transpile Input to a PostgreSQL WHERE Clause (returns Error Message on Failure)
+// NOTE: Input is not Verified, so Function Names should be Upper Case
+func transpilePostgresWhere(t *testing.T, input string, srid int) string {
+	tr := NewTranspileToPostgresWhere(parseFilter(t, input), columnMapper)
+	tr.SRID = srid
+
+	switch v := tr.Transpile().(type) {
+	case string:
+		return v
+	case *TranspilerError:
+		return v.Message
+	}
+
+	t.Fatalf("transpile [%s] unexpected result", input)
+	return ""
+}
+
+func TestPostgresComparisons(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`EQ(a, "abc")`, `t.a = 'abc'`},
+		{`EQ(a, "a*")`, `t.a = 'a%'`},
+		{`EQ(a, "a\*")`, `t.a = 'a*'`},
+		{`EQ(a, "a\\b")`, `t.a = 'a\b'`},
+		{`NEQ(a, "O'Neil \"Jr\"")`, `t.a != 'O''Neil "Jr"'`},
+		{`GT(n, 5)`, "t.n > 5"},
+		{`LTE(n, -1.5)`, "t.n <= -1.5"},
+		{`EQ(a, @b)`, "t.a = t.b"},
+		{`BETWEEN(a, "a", "it's")`, `t.a BETWEEN 'a' AND 'it''s'`},
+		{`BETWEEN(n, @n, 10)`, "t.n BETWEEN t.n AND 10"},
+		{`AND(EQ(a, "x"), NOT(OR(GT(n, 1), LT(n, 0))))`, `(t.a = 'x') AND (NOT((t.n > 1) OR (t.n < 0)))`},
+		{`LT(zz, 1)`, "Invalid Field [zz]"},
+		{`EQ(a, @zz)`, "Invalid Field [zz]"},
+		{`AND(EQ(a, "x"), GT(n, $1))`, "Unbound Parameter [$1]"},
+	}
+
+	for i, tt := range tests {
+		got := transpilePostgresWhere(t, tt.input, DEFAULT_SRID)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestPostgresStringOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`CONTAINS(a, "*abc*")`, `t.a LIKE '%abc%'`},
+		{`ICONTAINS(a, "*a_b%c*")`, `t.a ILIKE '%a\_b\%c%'`},
+		{`ICONTAINS(a, "*a\*b\\c*")`, `t.a ILIKE '%a*b\\c%'`},
+		{`STARTSWITH(a, "5%_off")`, `t.a LIKE '5\%\_off%'`},
+		{`ENDSWITH(a, "it's")`, `t.a LIKE '%it''s'`},
+		{`IEQ(a, "O'Neil")`, `LOWER(t.a) = LOWER('O''Neil')`},
+		{`IEQ(a, @b)`, "LOWER(t.a) = LOWER(t.b)"},
+		{`MATCHES(a, "^a.*$")`, `t.a ~ '^a.*$'`},
+		{`MATCHES(a, "a\*b")`, `t.a ~ 'a\*b'`},
+		{`MATCHES(a, "it's")`, `t.a ~ 'it''s'`},
+		{`IN(a, "x", "O'Neil")`, `t.a IN ('x', 'O''Neil')`},
+		{`IN(a, "x, O'Neil ,z")`, `t.a IN ('x', 'O''Neil', 'z')`},
+		{`IN(n, 1, 2, 3)`, "t.n IN (1, 2, 3)"},
+		{`SEARCH(a, "hello world")`, `to_tsvector(t.a) @@ to_tsquery('hello | world')`},
+		{`SEARCH(a, b, "+foo -bar* (x) a&b it's")`, `to_tsvector(concat_ws(' ', t.a, t.b)) @@ to_tsquery('foo | bar:* | x | ab | its')`},
+		{`ICONTAINS(zz, "x")`, "Invalid Field [zz]"},
+		{`SEARCH(a, zz, "x")`, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpilePostgresWhere(t, tt.input, DEFAULT_SRID)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestPostgresCollections(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`EXISTS(a)`, "t.a IS NOT NULL"},
+		{`MISSING(b)`, "t.b IS NULL"},
+		{`HAS(a, "x")`, `t.a @> jsonb_build_array('x')`},
+		{`HAS(a, "x,y")`, `t.a @> jsonb_build_array('x,y')`},
+		{`HAS_ALL(a, "x", "it's")`, `t.a @> jsonb_build_array('x', 'it''s')`},
+		{`HAS_ANY(n, 1)`, "t.n @> jsonb_build_array(1)"},
+		{`HAS_ANY(n, 1, "x")`, `(t.n @> jsonb_build_array(1) OR t.n @> jsonb_build_array('x'))`},
+		{`HAS_ANY(zz, 1)`, "Invalid Field [zz]"},
+		{`HAS(a, @zz)`, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpilePostgresWhere(t, tt.input, DEFAULT_SRID)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestPostgresGeo(t *testing.T) {
+	tests := []struct {
+		input    string
+		srid     int
+		expected string
+	}{
+		{`NEAR(loc, 40.7, -74.0, 500)`, DEFAULT_SRID, "ST_DistanceSphere(t.loc, ST_SetSRID(ST_MakePoint(-74.0, 40.7), 4326)) <= 500"},
+		{`NEAR(loc, 40.7, -74.0, 500)`, 0, "ST_DistanceSphere(t.loc, ST_MakePoint(-74.0, 40.7)) <= 500"},
+		{`WITHIN_BOX(loc, 10, -10, 20, 10)`, DEFAULT_SRID, "ST_Contains(ST_MakeEnvelope(-10, 10, 10, 20, 4326), t.loc)"},
+		{`WITHIN_BOX(loc, 10, -10, 20, 10)`, 0, "ST_Contains(ST_MakeEnvelope(-10, 10, 10, 20), t.loc)"},
+		{`WITHIN_BOX(loc, 10, 170, 20, -170)`, DEFAULT_SRID, "(ST_Contains(ST_MakeEnvelope(170, 10, 180, 20, 4326), t.loc) OR ST_Contains(ST_MakeEnvelope(-180, 10, -170, 20, 4326), t.loc))"},
+		{`NEAR(zz, 0, 0, 1)`, DEFAULT_SRID, "Invalid Field [zz]"},
+	}
+
+	for i, tt := range tests {
+		got := transpilePostgresWhere(t, tt.input, tt.srid)
+		if got != tt.expected {
+			t.Fatalf("tests[%d] - sql wrong. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}